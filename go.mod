@@ -12,4 +12,5 @@ require (
 	golang.org/x/sync v0.0.0-20200317015054-43a5402ce75a
 	golang.org/x/text v0.3.0
 	golang.org/x/tools v0.0.0-20200130002326-2f3ba24bd6e7
+	gopkg.in/yaml.v2 v2.2.2
 )
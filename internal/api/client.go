@@ -30,7 +30,7 @@ type doer interface {
 }
 
 type logger interface {
-	Debugf(msg string, keysAndValues ...interface{})
+	Debugw(msg string, keysAndValues ...interface{})
 }
 
 // Client client for working with the miflib api.
@@ -112,8 +112,8 @@ func (c *Client) DownloadFile(ctx context.Context, url, filename string) (err er
 		}
 		defer res.Body.Close()
 		if res.Header.Get("Content-Length") == strconv.FormatInt(info.Size(), 10) {
-			c.log.Debugf("skip downloading url %q because file %q exist with equal size: %d",
-				url, filename, info.Size())
+			c.log.Debugw("skip downloading the file which exists with the equal size",
+				"url", url, "path", filename, "bytes", info.Size())
 			return nil
 		}
 	} else if err != nil {
@@ -130,7 +130,7 @@ func (c *Client) DownloadFile(ctx context.Context, url, filename string) (err er
 		return err
 	}
 
-	c.log.Debugf("downloading data from url %q to file %q", url, filename)
+	c.log.Debugw("download the file", "url", url, "path", filename)
 
 	if req, err = http.NewRequest(http.MethodGet, url, nil); err != nil {
 		return err
@@ -162,7 +162,7 @@ func (c *Client) DownloadFile(ctx context.Context, url, filename string) (err er
 
 func (c *Client) doRequest(ctx context.Context, req *http.Request) (*http.Response, error) {
 	req = req.WithContext(ctx)
-	c.log.Debugf("http request is in progress, method: %q, url: %q", req.Method, req.URL)
+	c.log.Debugw("send the http request", "method", req.Method, "url", req.URL.String())
 	res, err := c.http.Do(req)
 	if err != nil {
 		return nil, err
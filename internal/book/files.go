@@ -10,3 +10,9 @@ type Files struct {
 	AudioBooks Formats `json:"audiobook"`
 	Demo       Formats `json:"demo"`
 }
+
+// Size returns the total size of the files of all categories, the files of
+// unknown size are not counted.
+func (f Files) Size() uint64 {
+	return f.Books.Size() + f.AudioBooks.Size() + f.Demo.Size()
+}
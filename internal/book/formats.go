@@ -2,6 +2,7 @@ package book
 
 import (
 	"encoding/json"
+	"sort"
 )
 
 // Formats it's relation file formats to their addresses.
@@ -14,3 +15,24 @@ func (m Formats) String() string {
 	}
 	return string(text)
 }
+
+// Names returns the sorted names of the formats.
+func (m Formats) Names() []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Size returns the total size of the files of all formats, the files of
+// unknown size are not counted.
+func (m Formats) Size() (n uint64) {
+	for _, addresses := range m {
+		for _, address := range addresses {
+			n += uint64(address.Size)
+		}
+	}
+	return n
+}
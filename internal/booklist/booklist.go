@@ -0,0 +1,167 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package booklist reads the lists of the books which should be kept in
+// the library.
+//
+// The list is a plain text file or a JSON document. Every line of the text
+// file is an entry: the line starting with a number is the identifier of
+// the book, the text after it is ignored and may contain the title for the
+// reader, other lines are the titles of the books. The titles starting
+// with a number are written in double quotes. Empty lines and lines
+// starting with # are skipped, for example:
+//
+//	# books for the new employees
+//	42 Джедайские техники
+//	Путь джедая
+//	"7 навыков высокоэффективных людей"
+//
+// The JSON document is an array of identifiers or objects with the id or
+// title fields, for example: [42, {"title": "Путь джедая"}].
+package booklist
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/xorcare/miflib.go/internal/book"
+	"github.com/xorcare/miflib.go/internal/norm"
+)
+
+// Entry it's an entry of the list, the book is identified by the ID or by
+// the Title if the ID is zero.
+type Entry struct {
+	ID    int    `json:"id,omitempty"`
+	Title string `json:"title,omitempty"`
+}
+
+// String returns the representation of the entry for the reports.
+func (e Entry) String() string {
+	switch {
+	case e.ID != 0 && e.Title != "":
+		return fmt.Sprintf("%d %q", e.ID, e.Title)
+	case e.ID != 0:
+		return strconv.Itoa(e.ID)
+	default:
+		return strconv.Quote(e.Title)
+	}
+}
+
+// UnmarshalJSON implements json.Unmarshaler, the entry may be a number.
+func (e *Entry) UnmarshalJSON(data []byte) error {
+	var id int
+	if err := json.Unmarshal(data, &id); err == nil {
+		*e = Entry{ID: id}
+		return nil
+	}
+
+	type entry Entry
+	return json.Unmarshal(data, (*entry)(e))
+}
+
+// Read reads the list from the file.
+func Read(filename string) ([]Entry, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("booklist: %s: %v", filename, err)
+	}
+
+	return entries, nil
+}
+
+// Parse parses the list, the format is detected by the first character.
+func Parse(data []byte) ([]Entry, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var entries []Entry
+		if err := json.Unmarshal(trimmed, &entries); err != nil {
+			return nil, err
+		}
+		for i, entry := range entries {
+			if entry.ID <= 0 && strings.TrimSpace(entry.Title) == "" {
+				return nil, fmt.Errorf("entry %d has neither a positive id nor a title", i+1)
+			}
+		}
+		return entries, nil
+	}
+
+	var entries []Entry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(text, `"`) {
+			title, err := strconv.Unquote(text)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid quoted title %s", line, text)
+			}
+			entries = append(entries, Entry{Title: title})
+			continue
+		}
+
+		first := strings.Fields(text)[0]
+		if strings.IndexFunc(first, func(r rune) bool { return !unicode.IsDigit(r) }) >= 0 {
+			entries = append(entries, Entry{Title: text})
+			continue
+		}
+
+		id, err := strconv.Atoi(first)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("line %d: invalid book id %q", line, first)
+		}
+		entries = append(entries, Entry{ID: id, Title: strings.TrimSpace(text[len(first):])})
+	}
+
+	return entries, scanner.Err()
+}
+
+// Match returns the books of the catalog listed in the entries keeping
+// their order and the entries which are not found in the catalog.
+func Match(entries []Entry, bks []book.Book) (matched []book.Book, missing []Entry) {
+	ids := make(map[int]bool, len(entries))
+	titles := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		if entry.ID != 0 {
+			ids[entry.ID] = true
+		} else {
+			titles[norm.Fold(entry.Title)] = true
+		}
+	}
+
+	foundIDs := make(map[int]bool, len(ids))
+	foundTitles := make(map[string]bool, len(titles))
+	for _, bk := range bks {
+		title := norm.Fold(bk.Title.String())
+		if ids[bk.ID] {
+			foundIDs[bk.ID] = true
+		}
+		if titles[title] {
+			foundTitles[title] = true
+		}
+		if ids[bk.ID] || titles[title] {
+			matched = append(matched, bk)
+		}
+	}
+
+	for _, entry := range entries {
+		if entry.ID != 0 && !foundIDs[entry.ID] || entry.ID == 0 && !foundTitles[norm.Fold(entry.Title)] {
+			missing = append(missing, entry)
+		}
+	}
+
+	return matched, missing
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package booklist
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/xorcare/miflib.go/internal/book"
+)
+
+func TestParse(t *testing.T) {
+	entries, err := Parse([]byte(`
+# books for the new employees
+42 Джедайские техники
+  180
+Путь джедая
+"7 навыков высокоэффективных людей"
+`))
+	require.NoError(t, err)
+	require.Equal(t, []Entry{
+		{ID: 42, Title: "Джедайские техники"},
+		{ID: 180},
+		{Title: "Путь джедая"},
+		{Title: "7 навыков высокоэффективных людей"},
+	}, entries)
+
+	entries, err = Parse([]byte(` [42, {"id": 180, "title": "Путь джедая"}, {"title": "Ёлки"}]`))
+	require.NoError(t, err)
+	require.Equal(t, []Entry{{ID: 42}, {ID: 180, Title: "Путь джедая"}, {Title: "Ёлки"}}, entries)
+
+	t.Run("error", func(t *testing.T) {
+		_, err := Parse([]byte("42\n0 Нулевая"))
+		require.EqualError(t, err, `line 2: invalid book id "0"`)
+
+		_, err = Parse([]byte(`"Незакрытая`))
+		require.EqualError(t, err, `line 1: invalid quoted title "Незакрытая`)
+
+		_, err = Parse([]byte(`[42, {}]`))
+		require.EqualError(t, err, `entry 2 has neither a positive id nor a title`)
+
+		_, err = Read("testdata/not-exist.txt")
+		require.Error(t, err)
+	})
+}
+
+func TestMatch(t *testing.T) {
+	catalog := []book.Book{
+		{ID: 42, Title: "Джедайские техники"},
+		{ID: 120, Title: "Ёлки-палки"},
+		{ID: 180, Title: "Путь джедая"},
+	}
+
+	matched, missing := Match([]Entry{
+		{ID: 180},
+		{Title: "елки-ПАЛКИ"},
+		{ID: 7, Title: "Удалённая книга"},
+		{Title: "Неизвестная книга"},
+		{ID: 180, Title: "Путь джедая"},
+	}, catalog)
+
+	require.Equal(t, []book.Book{catalog[1], catalog[2]}, matched)
+	require.Equal(t, []Entry{{ID: 7, Title: "Удалённая книга"}, {Title: "Неизвестная книга"}}, missing)
+	require.Equal(t, `7 "Удалённая книга"`, missing[0].String())
+	require.Equal(t, `"Неизвестная книга"`, missing[1].String())
+}
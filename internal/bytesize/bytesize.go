@@ -0,0 +1,96 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package bytesize parses and formats the sizes of the files, for example
+// 512, 100MB, 1.5GiB or 20G. The suffixes with i and the single letters are
+// binary units, 1K is 1024 bytes, the suffixes without i are decimal
+// units, 1KB is 1000 bytes.
+package bytesize
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Binary units of the size.
+const (
+	B   uint64 = 1
+	KiB        = 1024 * B
+	MiB        = 1024 * KiB
+	GiB        = 1024 * MiB
+	TiB        = 1024 * GiB
+)
+
+var units = map[string]uint64{
+	"":    B,
+	"b":   B,
+	"k":   KiB,
+	"kib": KiB,
+	"kb":  1000,
+	"m":   MiB,
+	"mib": MiB,
+	"mb":  1000 * 1000,
+	"g":   GiB,
+	"gib": GiB,
+	"gb":  1000 * 1000 * 1000,
+	"t":   TiB,
+	"tib": TiB,
+	"tb":  1000 * 1000 * 1000 * 1000,
+}
+
+// Parse parses the size, the empty string is zero.
+func Parse(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != '.'
+	})
+	if i < 0 {
+		i = len(s)
+	}
+
+	unit, ok := units[strings.ToLower(strings.TrimSpace(s[i:]))]
+	if !ok {
+		return 0, fmt.Errorf("bytesize: unknown unit of the size %q", s)
+	}
+
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("bytesize: invalid size %q", s)
+	}
+
+	size := n * float64(unit)
+	if size >= math.MaxUint64 {
+		return 0, fmt.Errorf("bytesize: size %q is too large", s)
+	}
+
+	return uint64(size), nil
+}
+
+// Format returns the size in the largest binary unit rounded down to one
+// decimal place, for example 1.5 GiB.
+func Format(n uint64) string {
+	for _, u := range []struct {
+		name string
+		size uint64
+	}{
+		{name: "TiB", size: TiB},
+		{name: "GiB", size: GiB},
+		{name: "MiB", size: MiB},
+		{name: "KiB", size: KiB},
+	} {
+		if n >= u.size {
+			value := math.Floor(float64(n)/float64(u.size)*10) / 10
+			return strconv.FormatFloat(value, 'f', -1, 64) + " " + u.name
+		}
+	}
+
+	return strconv.FormatUint(n, 10) + " B"
+}
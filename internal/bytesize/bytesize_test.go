@@ -0,0 +1,58 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bytesize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := map[string]uint64{
+		"":        0,
+		"512":     512,
+		"512B":    512,
+		"1k":      KiB,
+		"100MB":   100 * 1000 * 1000,
+		"1.5GiB":  GiB + GiB/2,
+		" 20 G ":  20 * GiB,
+		"2tib":    2 * TiB,
+		"0.5 kib": 512,
+	}
+	for s, want := range tests {
+		t.Run(s, func(t *testing.T) {
+			got, err := Parse(s)
+			require.NoError(t, err)
+			require.Equal(t, want, got)
+		})
+	}
+
+	for s, want := range map[string]string{
+		"10 PB": `bytesize: unknown unit of the size "10 PB"`,
+		"GiB":   `bytesize: invalid size "GiB"`,
+		"1.2.3": `bytesize: invalid size "1.2.3"`,
+		"1e30T": `bytesize: unknown unit of the size "1e30T"`,
+	} {
+		t.Run(s, func(t *testing.T) {
+			_, err := Parse(s)
+			require.EqualError(t, err, want)
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := map[uint64]string{
+		0:                 "0 B",
+		1023:              "1023 B",
+		KiB:               "1 KiB",
+		GiB + GiB/2:       "1.5 GiB",
+		2*TiB - 1:         "1.9 TiB",
+		100 * 1000 * 1000: "95.3 MiB",
+	}
+	for n, want := range tests {
+		require.Equal(t, want, Format(n), n)
+	}
+}
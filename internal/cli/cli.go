@@ -1,29 +1,31 @@
 package cli
 
 import (
-	"context"
-	"net/http"
-	"net/http/cookiejar"
-	"os"
-	"os/signal"
-	"sort"
+	gflag "flag"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/urfave/cli/v2"
 	"go.uber.org/zap"
-	"golang.org/x/net/publicsuffix"
-	"golang.org/x/sync/errgroup"
 
-	"github.com/xorcare/miflib.go/internal/api"
 	"github.com/xorcare/miflib.go/internal/book"
+	"github.com/xorcare/miflib.go/internal/booklist"
+	"github.com/xorcare/miflib.go/internal/bytesize"
 	"github.com/xorcare/miflib.go/internal/downloader"
 	"github.com/xorcare/miflib.go/internal/flag"
+	"github.com/xorcare/miflib.go/internal/ignore"
+	"github.com/xorcare/miflib.go/internal/library"
+	"github.com/xorcare/miflib.go/internal/logging"
+	"github.com/xorcare/miflib.go/internal/translit"
 )
 
 // New returns new instance of miflib application.
 func New(version string) *cli.App {
 	app := &cli.App{
 		Name:    "miflib",
-		Action:  action,
+		Before:  applyConfig,
+		Action:  syncAction,
 		Version: version,
 		Authors: []*cli.Author{
 			{
@@ -45,129 +47,282 @@ func New(version string) *cli.App {
 		flag.HTTPResponseHeaderTimeout,
 		flag.HTTPTimeout,
 		flag.Verbose,
+		flag.DirTemplate,
+		flag.FileTemplate,
+		flag.Filesystem,
+		flag.Translit,
+		flag.Include,
+		flag.EbookFormats,
+		flag.AudiobookFormats,
+		flag.DemoFormats,
+		flag.Filter,
+		flag.ListFile,
+		flag.Prune,
+		flag.DryRun,
+		flag.MinFreeSpace,
+		flag.SpaceCheck,
+		flag.Quota,
+		flag.Config,
+		flag.Profile,
+		flag.PasswordFile,
+		flag.PasswordStdin,
+		flag.PasswordCommand,
+		flag.LogFormat,
+		flag.LogFile,
+		flag.LogMaxSize,
+		flag.LogMaxBackups,
+		flag.LogLevel,
+		flag.LogSampling,
+	}
+
+	app.Commands = []*cli.Command{
+		syncCommand(),
+		infoCommand(),
+		listCommand(),
+		statusCommand(),
+		verifyCommand(),
+		historyCommand(),
+		migrateCommand(),
+		configCommand(),
 	}
 
 	return app
 }
 
-func action(c *cli.Context) error {
-	loggerConf := zap.Config{
-		Level:       zap.NewAtomicLevelAt(zap.InfoLevel),
-		Development: false,
-		Sampling: &zap.SamplingConfig{
-			Initial:    100,
-			Thereafter: 100,
-		},
-		Encoding:          "console",
-		EncoderConfig:     zap.NewDevelopmentEncoderConfig(),
-		OutputPaths:       []string{"stderr"},
-		ErrorOutputPaths:  []string{"stderr"},
-		DisableCaller:     true,
-		DisableStacktrace: true,
+// The flags of the commands, the commands declare the global flags once
+// more to accept them after the name of the command.
+var (
+	connectionFlags = []cli.Flag{
+		flag.Username,
+		flag.Password,
+		flag.Hostname,
+		flag.HTTPResponseHeaderTimeout,
+		flag.HTTPTimeout,
+		flag.PasswordFile,
+		flag.PasswordStdin,
+		flag.PasswordCommand,
+	}
+	logFlags = []cli.Flag{
+		flag.Verbose,
+		flag.LogFormat,
+		flag.LogFile,
+		flag.LogMaxSize,
+		flag.LogMaxBackups,
+		flag.LogLevel,
+		flag.LogSampling,
 	}
+	layoutFlags = []cli.Flag{
+		flag.Directory,
+		flag.DirTemplate,
+		flag.FileTemplate,
+		flag.Filesystem,
+		flag.Translit,
+	}
+	selectionFlags = []cli.Flag{
+		flag.Filter,
+		flag.ListFile,
+	}
+	downloadFlags = []cli.Flag{
+		flag.NumThreads,
+		flag.Include,
+		flag.EbookFormats,
+		flag.AudiobookFormats,
+		flag.DemoFormats,
+		flag.Prune,
+		flag.DryRun,
+		flag.MinFreeSpace,
+		flag.SpaceCheck,
+		flag.Quota,
+	}
+)
 
-	if c.Bool(flag.Verbose.Name) {
-		loggerConf.Level = zap.NewAtomicLevelAt(zap.DebugLevel)
+// commandFlags returns the flags of the command joined from the lists.
+func commandFlags(lists ...[]cli.Flag) []cli.Flag {
+	var flags []cli.Flag
+	for _, list := range lists {
+		flags = append(flags, list...)
 	}
+	return flags
+}
 
-	logger, _ := loggerConf.Build()
-	sugar := logger.Sugar()
-	defer logger.Sync()
+// timeFormat it's the layout of the time printed by the commands.
+const timeFormat = time.RFC3339
 
-	ch := make(chan book.Book)
+// requireFlags checks that the flags are set, the credentials are not
+// required by the application as a whole, since some commands work only
+// with the local library.
+func requireFlags(c *cli.Context, names ...string) error {
+	var missing []string
+	for _, name := range names {
+		if !c.IsSet(name) {
+			missing = append(missing, name)
+		}
+	}
 
-	ctx, done := context.WithCancel(context.Background())
+	switch len(missing) {
+	case 0:
+		return nil
+	case 1:
+		return fmt.Errorf("required flag %q not set", missing[0])
+	default:
+		return fmt.Errorf(`required flags "%s" not set`, strings.Join(missing, `", "`))
+	}
+}
 
-	quit := make(chan os.Signal, 1)
+// inheritFlags it's the Before of the commands which declare the global
+// flags once more, the values set before the command in the command line,
+// in the environment or by the profile are copied to the flags of the
+// command, the values set after the command take precedence and are
+// recorded as set by the command line.
+func inheritFlags(c *cli.Context) error {
+	visited := make(map[string]bool)
+	for _, name := range c.LocalFlagNames() {
+		visited[name] = true
+	}
+	s, _ := c.App.Metadata[settingsKey].(*settings)
 
-	signal.Notify(quit, os.Interrupt)
+	parent := c.Lineage()[1]
+	for _, f := range c.Command.Flags {
+		name := f.Names()[0]
+		if anyVisited(visited, f.Names()) {
+			if s != nil {
+				s.sources[name] = sourceFlag
+			}
+			continue
+		}
+		if !parent.IsSet(name) {
+			continue
+		}
 
-	go func() {
-		<-quit
-		logger.Info("miflib is shutting down by os interrupt signal...")
-		done()
-		// You need to completely subtract the channel for successful completion
-		// in the event of an interruption of the program.
-		for range ch {
+		value, ok := parent.Generic(name).(gflag.Value)
+		if !ok {
+			continue
 		}
-	}()
-	jar, err := cookiejar.New(
-		&cookiejar.Options{
-			PublicSuffixList: publicsuffix.List,
-		},
-	)
+		text := value.String()
+		if v, ok := value.(cli.Serializer); ok {
+			text = v.Serialize()
+		}
+		if err := c.Set(name, text); err != nil {
+			return fmt.Errorf("invalid value %q of the flag %q: %v", text, name, err)
+		}
+	}
+
+	return nil
+}
+
+// newLayout creates the layout of the library configured by the flags.
+func newLayout(c *cli.Context, dir, file, filesystem, scheme *cli.StringFlag) (downloader.Layout, error) {
+	layout, err := downloader.NewLayout(c.String(dir.Name), c.String(file.Name))
 	if err != nil {
-		return err
+		return downloader.Layout{}, err
 	}
 
-	apiClient := api.NewClient(
-		"https://"+c.String(flag.Hostname.Name),
-		sugar,
-		api.OptDoer(
-			&http.Client{
-				Timeout: c.Duration(flag.HTTPTimeout.Name),
-				Transport: &http.Transport{
-					ResponseHeaderTimeout: c.Duration(flag.HTTPResponseHeaderTimeout.Name),
-				},
-				Jar: jar,
-			},
-		),
-	)
-
-	if err := apiClient.Login(
-		ctx,
-		c.String(flag.Username.Name),
-		c.String(flag.Password.Name),
-	); err != nil {
-		return err
+	profile, err := downloader.LookupProfile(c.String(filesystem.Name))
+	if err != nil {
+		return downloader.Layout{}, err
 	}
 
-	wg, ctx := errgroup.WithContext(ctx)
+	tr, err := translit.Lookup(c.String(scheme.Name))
+	if err != nil {
+		return downloader.Layout{}, err
+	}
 
-	loader := downloader.NewLoader(c.String(flag.Directory.Name), apiClient, sugar)
-	for i := 0; i < c.Int(flag.NumThreads.Name); i++ {
-		wg.Go(
-			func() error {
-				return loader.Worker(ctx, ch)
-			},
-		)
+	return layout.WithProfile(profile.WithTranslit(tr)), nil
+}
+
+// ignored returns the books which are not ignored by the rules keeping
+// their order, every skipped book is logged.
+func ignored(rules ignore.Rules, bks []book.Book, log *zap.SugaredLogger) []book.Book {
+	books := make([]book.Book, 0, len(bks))
+	for _, bk := range bks {
+		if ignored, by := rules.Book(bk); ignored {
+			log.Infow("skip the ignored book", "book_id", bk.ID, "title", bk.Title, "rule", by)
+			continue
+		}
+		books = append(books, bk)
 	}
 
-	wg.Go(
-		func() error {
-			defer close(ch)
-			bks, err := apiClient.List(ctx)
-			if err != nil {
-				return err
-			}
+	return books
+}
 
-			sort.Slice(
-				bks.Books, func(i, j int) bool {
-					return bks.Books[i].ID < bks.Books[j].ID
-				},
-			)
+// prune removes from the library the books which are not in the list,
+// the books are only logged in the dry run.
+func prune(root string, list []booklist.Entry, catalog []book.Book, dryRun bool, log *zap.SugaredLogger) error {
+	keep := make(map[int]bool, len(list))
+	for _, entry := range list {
+		keep[entry.ID] = true
+	}
+	listed, _ := booklist.Match(list, catalog)
+	for _, bk := range listed {
+		keep[bk.ID] = true
+	}
+
+	entries, err := library.Scan(root)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if keep[entry.Book.ID] {
+			continue
+		}
+		if dryRun {
+			log.Infow("would remove the book which is not in the list", "book_id", entry.Book.ID, "path", entry.Path)
+			continue
+		}
+		log.Infow("remove the book which is not in the list", "book_id", entry.Book.ID, "path", entry.Path)
+		if err := library.Remove(root, entry); err != nil {
+			return err
+		}
+	}
 
-			sugar.Infof("currently %d books are available for download", bks.Total)
+	return nil
+}
 
-			for i, bk := range bks.Books {
-				sugar.Infof("%d books are waiting to be downloaded", int(bks.Total)-i)
+// loaderOptions returns the options of the loader configured by the flags.
+func loaderOptions(c *cli.Context) ([]downloader.Option, error) {
+	categories, err := downloader.ParseCategories(c.String(flag.Include.Name))
+	if err != nil {
+		return nil, err
+	}
 
-				select {
-				case <-ctx.Done():
-					return ctx.Err()
-				case ch <- bk:
-				}
-			}
+	opts := []downloader.Option{downloader.OptCategories(categories...)}
+	for category, fl := range map[downloader.Category]*cli.StringFlag{
+		downloader.CategoryEbook:     flag.EbookFormats,
+		downloader.CategoryAudiobook: flag.AudiobookFormats,
+		downloader.CategoryDemo:      flag.DemoFormats,
+	} {
+		rule, err := downloader.ParseFormatRule(c.String(fl.Name))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of the flag %q: %v", fl.Name, err)
+		}
+		opts = append(opts, downloader.OptFormatRule(category, rule))
+	}
 
-			return nil
-		},
-	)
+	return opts, nil
+}
 
-	if err := wg.Wait(); err != nil {
-		return err
+// newLogger creates the loggers of the subsystems configured by the flags.
+func newLogger(c *cli.Context) (*logging.Logger, error) {
+	levels, err := logging.ParseLevels(c.String(flag.LogLevel.Name))
+	if err != nil {
+		return nil, err
+	}
+	if c.Bool(flag.Verbose.Name) {
+		levels[""] = zap.DebugLevel
 	}
 
-	logger.Info("correct completion of downloading")
+	maxSize, err := bytesize.Parse(c.String(flag.LogMaxSize.Name))
+	if err != nil {
+		return nil, fmt.Errorf("invalid value of the flag %q: %v", flag.LogMaxSize.Name, err)
+	}
 
-	return nil
+	return logging.New(logging.Config{
+		Format:     c.String(flag.LogFormat.Name),
+		File:       c.String(flag.LogFile.Name),
+		MaxSize:    maxSize,
+		MaxBackups: c.Int(flag.LogMaxBackups.Name),
+		Levels:     levels,
+		Sampling:   c.Bool(flag.LogSampling.Name),
+	})
 }
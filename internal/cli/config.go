@@ -0,0 +1,224 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+
+	"github.com/xorcare/miflib.go/internal/config"
+	"github.com/xorcare/miflib.go/internal/flag"
+	"github.com/xorcare/miflib.go/internal/flags"
+)
+
+// settingsKey it's the key of the metadata of the application with the
+// applied configuration.
+const settingsKey = "settings"
+
+// The sources of the values of the flags.
+const (
+	sourceFlag    = "flag"
+	sourceEnv     = "env"
+	sourceProfile = "profile"
+	sourceDefault = "default"
+)
+
+// sourceRanks it's the precedence of the sources, the value of the source
+// of the higher rank wins.
+var sourceRanks = map[string]int{
+	sourceDefault: 0,
+	sourceProfile: 1,
+	sourceEnv:     2,
+	sourceFlag:    3,
+}
+
+// secretFlags it's the flags which values are masked in the output.
+var secretFlags = map[string]bool{
+	flags.Password: true,
+}
+
+// settings it's the configuration applied to the flags.
+type settings struct {
+	file    string
+	profile string
+	// sources is the sources of the values by the names of the flags.
+	sources map[string]string
+}
+
+func configCommand() *cli.Command {
+	return &cli.Command{
+		Name:   "config",
+		Usage:  "prints the effective values of the global flags, the secrets are masked",
+		Action: configAction,
+	}
+}
+
+// applyConfig sets the values of the profile of the configuration file to
+// the flags which are set neither in the command line nor in the
+// environment.
+func applyConfig(c *cli.Context) error {
+	s := &settings{sources: make(map[string]string, len(c.App.Flags))}
+	c.App.Metadata[settingsKey] = s
+
+	visited := make(map[string]bool)
+	for _, name := range c.LocalFlagNames() {
+		visited[name] = true
+	}
+	flagsByName := make(map[string]cli.Flag, len(c.App.Flags))
+	for _, f := range c.App.Flags {
+		name := f.Names()[0]
+		flagsByName[name] = f
+		switch {
+		case anyVisited(visited, f.Names()):
+			s.sources[name] = sourceFlag
+		case c.IsSet(name):
+			s.sources[name] = sourceEnv
+		default:
+			s.sources[name] = sourceDefault
+		}
+	}
+
+	s.file = c.String(flag.Config.Name)
+	if s.file == "" {
+		if s.file = config.DefaultPath(); s.file == "" {
+			return nil
+		}
+	}
+
+	file, err := config.Read(s.file)
+	if os.IsNotExist(err) && !c.IsSet(flag.Config.Name) {
+		s.file = ""
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	profile, err := file.Lookup(c.String(flag.Profile.Name))
+	if err != nil {
+		return err
+	}
+	if s.profile = c.String(flag.Profile.Name); s.profile == "" {
+		s.profile = file.Profile
+	}
+
+	values, err := profile.Values()
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		source, ok := s.sources[key]
+		switch {
+		case key == flag.Config.Name || key == flag.Profile.Name:
+			return fmt.Errorf("config: the flag %q can't be set in the profile %q", key, s.profile)
+		case !ok:
+			return fmt.Errorf("config: unknown flag %q in the profile %q", key, s.profile)
+		case source != sourceDefault:
+			continue
+		}
+
+		list := values[key]
+		if _, ok := flagsByName[key].(*cli.StringSliceFlag); !ok {
+			// the flags which take one value accept the comma separated lists.
+			list = []string{strings.Join(list, ",")}
+		}
+		for _, value := range list {
+			if err := c.Set(key, value); err != nil {
+				return fmt.Errorf("config: invalid value %q of the flag %q in the profile %q: %v",
+					value, key, s.profile, err)
+			}
+		}
+		s.sources[key] = sourceProfile
+	}
+
+	return nil
+}
+
+func anyVisited(visited map[string]bool, names []string) bool {
+	for _, name := range names {
+		if visited[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// flagSource returns the source of the value of the flag recorded by the
+// applyConfig.
+func flagSource(c *cli.Context, name string) string {
+	if s, ok := c.App.Metadata[settingsKey].(*settings); ok {
+		if source, ok := s.sources[name]; ok {
+			return source
+		}
+	}
+	if c.IsSet(name) {
+		return sourceFlag
+	}
+	return sourceDefault
+}
+
+func configAction(c *cli.Context) error {
+	s, ok := c.App.Metadata[settingsKey].(*settings)
+	if !ok {
+		s = &settings{}
+	}
+
+	w := c.App.Writer
+	switch {
+	case s.file == "":
+		fmt.Fprintln(w, "# no configuration file")
+	case s.profile == "":
+		fmt.Fprintf(w, "# configuration file %q, no profile\n", s.file)
+	default:
+		fmt.Fprintf(w, "# configuration file %q, profile %q\n", s.file, s.profile)
+	}
+
+	for _, f := range c.App.Flags {
+		name := f.Names()[0]
+		if f == cli.HelpFlag || f == cli.VersionFlag {
+			continue
+		}
+		value := flagValue(c, f)
+		if secretFlags[name] && value != "" {
+			value = "******"
+		}
+
+		data, err := yaml.Marshal(map[string]interface{}{name: value})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s # %s\n", strings.TrimSuffix(string(data), "\n"), s.sources[name])
+	}
+
+	return nil
+}
+
+// flagValue returns the value of the flag.
+func flagValue(c *cli.Context, f cli.Flag) interface{} {
+	name := f.Names()[0]
+	switch f.(type) {
+	case *cli.BoolFlag:
+		return c.Bool(name)
+	case *cli.IntFlag:
+		return c.Int(name)
+	case *cli.DurationFlag:
+		return c.Duration(name).String()
+	case *cli.StringSliceFlag:
+		return c.StringSlice(name)
+	default:
+		return c.String(name)
+	}
+}
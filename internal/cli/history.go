@@ -0,0 +1,79 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/xorcare/miflib.go/internal/flag"
+	"github.com/xorcare/miflib.go/internal/history"
+	"github.com/xorcare/miflib.go/internal/library"
+)
+
+func historyCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "history",
+		Usage:     "shows what changed in the catalog entry of the book over time",
+		ArgsUsage: "<book id>",
+		Action:    historyAction,
+		Before:    inheritFlags,
+		Flags:     []cli.Flag{flag.Directory},
+	}
+}
+
+func historyAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("the book id is required")
+	}
+	id, err := strconv.Atoi(c.Args().First())
+	if err != nil {
+		return fmt.Errorf("invalid book id %q: %v", c.Args().First(), err)
+	}
+
+	entry, err := library.Find(c.String(flag.Directory.Name), id)
+	if err != nil {
+		return err
+	}
+
+	versions, err := history.Versions(entry.Path)
+	if err != nil {
+		return err
+	}
+
+	w := c.App.Writer
+	fmt.Fprintf(w, "%05d %s\n", entry.Book.ID, entry.Book.Title)
+	if len(versions) == 1 {
+		fmt.Fprintf(w, "no changes recorded since %s\n", versions[0].Time.Format(timeFormat))
+		return nil
+	}
+
+	for i := 1; i < len(versions); i++ {
+		old, err := ioutil.ReadFile(versions[i-1].Path)
+		if err != nil {
+			return err
+		}
+		cur, err := ioutil.ReadFile(versions[i].Path)
+		if err != nil {
+			return err
+		}
+		changes, err := history.Diff(old, cur)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(w, "\n--- %s\n+++ %s\n",
+			versions[i-1].Time.Format(timeFormat), versions[i].Time.Format(timeFormat))
+		for _, change := range changes {
+			fmt.Fprintln(w, change)
+		}
+	}
+
+	return nil
+}
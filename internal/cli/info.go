@@ -0,0 +1,73 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/urfave/cli/v2"
+)
+
+func infoCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "info",
+		Usage:     "prints the catalog entry of the book as JSON",
+		ArgsUsage: "<book id>",
+		Action:    infoAction,
+		Before:    inheritFlags,
+		Flags:     commandFlags(connectionFlags, logFlags),
+	}
+}
+
+func infoAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("the book id is required")
+	}
+	id, err := strconv.Atoi(c.Args().First())
+	if err != nil {
+		return fmt.Errorf("invalid book id %q: %v", c.Args().First(), err)
+	}
+
+	username, password, err := credentials(context.Background(), c)
+	if err != nil {
+		return err
+	}
+
+	logs, err := newLogger(c)
+	if err != nil {
+		return err
+	}
+	defer logs.Close()
+
+	ctx := context.Background()
+	client, err := connect(ctx, c, logs, username, password)
+	if err != nil {
+		return err
+	}
+
+	bks, err := client.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, bk := range bks.Books {
+		if bk.ID != id {
+			continue
+		}
+
+		data, err := json.MarshalIndent(bk, "", "\t")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(c.App.Writer, "%s\n", data)
+		return err
+	}
+
+	return fmt.Errorf("the book %d is not found in the catalog", id)
+}
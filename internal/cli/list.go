@@ -0,0 +1,275 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/xorcare/miflib.go/internal/book"
+	"github.com/xorcare/miflib.go/internal/bytesize"
+	"github.com/xorcare/miflib.go/internal/flag"
+	"github.com/xorcare/miflib.go/internal/library"
+	"github.com/xorcare/miflib.go/internal/logging"
+)
+
+// The formats of the output of the list.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputCSV   = "csv"
+)
+
+// The states of the book in the local library.
+const (
+	localMissing  = "missing"
+	localPartial  = "partial"
+	localComplete = "complete"
+)
+
+// listing it's the row of the list of the books.
+type listing struct {
+	ID        int      `json:"id"`
+	Title     string   `json:"title"`
+	Authors   []string `json:"authors"`
+	Ebook     []string `json:"ebook"`
+	Audiobook []string `json:"audiobook"`
+	// Size is the total size of the files of the book.
+	Size  uint64 `json:"size"`
+	Local string `json:"local"`
+}
+
+func listCommand() *cli.Command {
+	return &cli.Command{
+		Name: "list",
+		Usage: "prints the books of the catalog selected by the same flags as the sync," +
+			" the books present in the --directory are marked",
+		Before: inheritFlags,
+		Action: listAction,
+		Flags: commandFlags(
+			connectionFlags, logFlags, []cli.Flag{flag.Directory}, selectionFlags,
+			[]cli.Flag{flag.Output, flag.Sort},
+		),
+	}
+}
+
+func listAction(c *cli.Context) error {
+	output := c.String(flag.Output.Name)
+	switch output {
+	case outputTable, outputJSON, outputCSV:
+	default:
+		return fmt.Errorf("invalid value %q of the flag %q, expected %s, %s or %s",
+			output, flag.Output.Name, outputTable, outputJSON, outputCSV)
+	}
+
+	less, err := listOrder(c.String(flag.Sort.Name))
+	if err != nil {
+		return err
+	}
+
+	sel, err := newSelection(c)
+	if err != nil {
+		return err
+	}
+
+	username, password, err := credentials(context.Background(), c)
+	if err != nil {
+		return err
+	}
+
+	logs, err := newLogger(c)
+	if err != nil {
+		return err
+	}
+	defer logs.Close()
+	sugar := logs.Named(logging.CLI).Sugar()
+
+	local, err := localBooks(c.String(flag.Directory.Name))
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	client, err := connect(ctx, c, logs, username, password)
+	if err != nil {
+		return err
+	}
+
+	bks, err := client.List(ctx)
+	if err != nil {
+		return err
+	}
+	sort.Slice(bks.Books, func(i, j int) bool {
+		return bks.Books[i].ID < bks.Books[j].ID
+	})
+
+	books := ignored(sel.rules, sel.books(bks.Books, sugar), sugar)
+
+	rows := make([]listing, 0, len(books))
+	for _, bk := range books {
+		rows = append(rows, newListing(bk, local))
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return less(rows[i], rows[j])
+	})
+
+	switch output {
+	case outputJSON:
+		return writeJSONLines(c.App.Writer, rows)
+	case outputCSV:
+		return writeCSV(c.App.Writer, rows)
+	default:
+		return writeTable(c.App.Writer, rows)
+	}
+}
+
+// localBooks returns the books of the local library by the identifiers,
+// the missing library is empty.
+func localBooks(root string) (map[int]library.Entry, error) {
+	entries, err := library.Scan(root)
+	if os.IsNotExist(err) {
+		return map[int]library.Entry{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	local := make(map[int]library.Entry, len(entries))
+	for _, entry := range entries {
+		local[entry.Book.ID] = entry
+	}
+
+	return local, nil
+}
+
+// localState returns the state of the book in the local library.
+func localState(id int, local map[int]library.Entry) string {
+	entry, ok := local[id]
+	switch {
+	case !ok:
+		return localMissing
+	case entry.Downloaded:
+		return localComplete
+	default:
+		return localPartial
+	}
+}
+
+func newListing(bk book.Book, local map[int]library.Entry) listing {
+	row := listing{
+		ID:        bk.ID,
+		Title:     bk.Title.String(),
+		Authors:   make([]string, 0, len(bk.Authors)),
+		Ebook:     bk.Files.Books.Names(),
+		Audiobook: bk.Files.AudioBooks.Names(),
+		Size:      bk.Files.Size(),
+		Local:     localState(bk.ID, local),
+	}
+	for _, author := range bk.Authors {
+		row.Authors = append(row.Authors, author.Name)
+	}
+
+	return row
+}
+
+// listOrder returns the ordering of the rows by the value of the --sort.
+func listOrder(key string) (func(a, b listing) bool, error) {
+	reverse := strings.HasPrefix(key, "-")
+
+	var less func(a, b listing) bool
+	switch strings.TrimPrefix(key, "-") {
+	case "id":
+		less = func(a, b listing) bool { return a.ID < b.ID }
+	case "title":
+		less = func(a, b listing) bool { return strings.ToLower(a.Title) < strings.ToLower(b.Title) }
+	case "author":
+		less = func(a, b listing) bool {
+			return strings.ToLower(strings.Join(a.Authors, ", ")) < strings.ToLower(strings.Join(b.Authors, ", "))
+		}
+	case "size":
+		less = func(a, b listing) bool { return a.Size < b.Size }
+	default:
+		return nil, fmt.Errorf("invalid value %q of the flag %q, expected id, title, author or size",
+			key, flag.Sort.Name)
+	}
+
+	if reverse {
+		return func(a, b listing) bool { return less(b, a) }, nil
+	}
+
+	return less, nil
+}
+
+func writeTable(w io.Writer, rows []listing) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTITLE\tAUTHORS\tEBOOK\tAUDIOBOOK\tSIZE\tLOCAL")
+
+	var total uint64
+	for _, row := range rows {
+		total += row.Size
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", row.ID, row.Title,
+			orDash(strings.Join(row.Authors, ", ")), orDash(strings.Join(row.Ebook, ",")),
+			orDash(strings.Join(row.Audiobook, ",")), bytesize.Format(row.Size), row.Local)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "total: %d books, %s\n", len(rows), bytesize.Format(total))
+	return err
+}
+
+func writeJSONLines(w io.Writer, rows []listing) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for _, row := range rows {
+		if err := enc.Encode(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeCSV(w io.Writer, rows []listing) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"id", "title", "authors", "ebook", "audiobook", "size", "local"}); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		err := cw.Write([]string{
+			strconv.Itoa(row.ID),
+			row.Title,
+			strings.Join(row.Authors, ", "),
+			strings.Join(row.Ebook, ","),
+			strings.Join(row.Audiobook, ","),
+			strconv.FormatUint(row.Size, 10),
+			row.Local,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// orDash returns the dash instead of the empty string to keep the columns
+// of the table readable.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/xorcare/miflib.go/internal/downloader"
+	"github.com/xorcare/miflib.go/internal/flag"
+	"github.com/xorcare/miflib.go/internal/logging"
+)
+
+func migrateCommand() *cli.Command {
+	return &cli.Command{
+		Name: "migrate",
+		Usage: "moves the downloaded books from the previous layout to the current one," +
+			" the previous layout is set by the --from-* flags",
+		Before: inheritFlags,
+		Action: migrateAction,
+		Flags: commandFlags(logFlags, layoutFlags, []cli.Flag{
+			flag.FromDirTemplate,
+			flag.FromFileTemplate,
+			flag.FromFilesystem,
+			flag.FromTranslit,
+			flag.DryRun,
+		}),
+	}
+}
+
+func migrateAction(c *cli.Context) error {
+	from, err := newLayout(c, flag.FromDirTemplate, flag.FromFileTemplate, flag.FromFilesystem, flag.FromTranslit)
+	if err != nil {
+		return err
+	}
+
+	to, err := newLayout(c, flag.DirTemplate, flag.FileTemplate, flag.Filesystem, flag.Translit)
+	if err != nil {
+		return err
+	}
+
+	logs, err := newLogger(c)
+	if err != nil {
+		return err
+	}
+	defer logs.Close()
+
+	loader := downloader.NewLoader(
+		c.String(flag.Directory.Name),
+		nil,
+		logs.Named(logging.Downloader).Sugar(),
+		downloader.OptLayout(to),
+	)
+
+	dryRun := c.Bool(flag.DryRun.Name)
+	moves, err := loader.Migrate(from, dryRun)
+
+	var moved, conflicts int
+	for _, move := range moves {
+		fmt.Fprintln(c.App.Writer, move)
+		if move.Err != nil {
+			conflicts++
+		} else {
+			moved++
+		}
+	}
+
+	if dryRun {
+		fmt.Fprintf(c.App.Writer, "%d moves would be made, %d conflicts\n", moved, conflicts)
+	} else {
+		fmt.Fprintf(c.App.Writer, "%d moves are made, %d conflicts\n", moved, conflicts)
+	}
+
+	return err
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/xorcare/miflib.go/internal/library"
+)
+
+func TestMigrate_dryRun(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	// the configuration file of the user must not affect the test.
+	defer os.Setenv("XDG_CONFIG_HOME", os.Getenv("XDG_CONFIG_HOME"))
+	require.NoError(t, os.Setenv("XDG_CONFIG_HOME", tempDir))
+
+	root := filepath.Join(tempDir, "lib")
+	bookpath := filepath.Join(root, "00001 Test")
+	require.NoError(t, os.MkdirAll(bookpath, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(bookpath, library.BookFile), []byte(`{"id": 1, "title": "Test"}`), 0644))
+
+	profile := filepath.Join(tempDir, "config.yaml")
+	require.NoError(t, ioutil.WriteFile(profile, []byte("profile: safe\nprofiles:\n  safe:\n    dry-run: true\n"), 0644))
+
+	layout := []string{"-d", root, "--dir-template", "{{.ID}}"}
+	for name, args := range map[string][]string{
+		"before the command": append(append([]string{}, layout...), "--dry-run", "migrate"),
+		"after the command":  append(append([]string{}, layout...), "migrate", "--dry-run"),
+		"profile":            append(append([]string{}, layout...), "--config", profile, "migrate"),
+		"all after":          append(append([]string{"migrate"}, layout...), "--dry-run"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			app := New("test")
+			buf := bytes.Buffer{}
+			app.Writer = &buf
+
+			require.NoError(t, app.Run(append([]string{"miflib"}, args...)))
+			require.Contains(t, buf.String(), "1 moves would be made")
+			require.DirExists(t, bookpath)
+			require.NoDirExists(t, filepath.Join(root, "1"))
+		})
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+	"go.uber.org/zap"
+
+	"github.com/xorcare/miflib.go/internal/bytesize"
+	"github.com/xorcare/miflib.go/internal/downloader"
+	"github.com/xorcare/miflib.go/internal/flag"
+)
+
+// newQuota creates the quota configured by the flags, the nil quota is
+// unlimited.
+func newQuota(c *cli.Context) (*downloader.Quota, error) {
+	if !c.IsSet(flag.Quota.Name) {
+		return nil, nil
+	}
+
+	limit, err := bytesize.Parse(c.String(flag.Quota.Name))
+	if err != nil {
+		return nil, fmt.Errorf("invalid value of the flag %q: %v", flag.Quota.Name, err)
+	}
+
+	return downloader.NewQuota(limit), nil
+}
+
+// reportQuota reports the usage of the quota and the books which are left
+// for the next run.
+func reportQuota(quota *downloader.Quota, log *zap.SugaredLogger) {
+	if quota == nil {
+		return
+	}
+
+	log.Infow("the download quota is used", "bytes", quota.Used(), "quota", quota.Limit())
+	if !quota.Exceeded() {
+		return
+	}
+
+	backlog := quota.Backlog()
+	log.Warnw("the download quota is reached, the books are left for the next run", "books", len(backlog))
+	for _, bk := range backlog {
+		log.Infow("the book is left for the next run", "book_id", bk.ID, "title", bk.Title)
+	}
+}
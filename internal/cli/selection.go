@@ -0,0 +1,87 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"path/filepath"
+
+	"github.com/urfave/cli/v2"
+	"go.uber.org/zap"
+
+	"github.com/xorcare/miflib.go/internal/book"
+	"github.com/xorcare/miflib.go/internal/booklist"
+	"github.com/xorcare/miflib.go/internal/filter"
+	"github.com/xorcare/miflib.go/internal/flag"
+	"github.com/xorcare/miflib.go/internal/ignore"
+	"github.com/xorcare/miflib.go/internal/library"
+)
+
+// selection it's the books of the catalog chosen by the --filter, the
+// --list-file and the ignore file of the library.
+type selection struct {
+	expr     filter.Expr
+	list     []booklist.Entry
+	listFile string
+	rules    ignore.Rules
+}
+
+// newSelection reads the selection configured by the flags, the list is
+// nil if the --list-file is not set.
+func newSelection(c *cli.Context) (selection, error) {
+	expr, err := filter.Parse(c.String(flag.Filter.Name))
+	if err != nil {
+		return selection{}, err
+	}
+
+	rules, err := ignore.Read(filepath.Join(c.String(flag.Directory.Name), library.IgnoreFile))
+	if err != nil {
+		return selection{}, err
+	}
+
+	s := selection{expr: expr, rules: rules, listFile: c.String(flag.ListFile.Name)}
+	if s.listFile != "" {
+		if s.list, err = booklist.Read(s.listFile); err != nil {
+			return selection{}, err
+		}
+	}
+
+	return s, nil
+}
+
+// books returns the books of the catalog which match the filter and the
+// list keeping their order, the books of the list missing in the whole
+// catalog are logged. The ignore rules are not applied.
+func (s selection) books(catalog []book.Book, log *zap.SugaredLogger) []book.Book {
+	books := s.expr.Books(catalog)
+	if s.expr.String() != "" {
+		log.Infow("the books match the filter", "books", len(books), "filter", s.expr.String())
+	}
+	if s.list == nil {
+		return books
+	}
+
+	matched, missing := booklist.Match(s.list, catalog)
+	for _, entry := range missing {
+		log.Warnw("the book from the list is not found in the catalog",
+			"book_id", entry.ID, "title", entry.Title, "path", s.listFile)
+	}
+	log.Infow("the books of the list are found in the catalog", "books", len(matched))
+
+	filtered := make(map[int]bool, len(books))
+	for _, bk := range books {
+		filtered[bk.ID] = true
+	}
+	listed := matched[:0]
+	for _, bk := range matched {
+		if filtered[bk.ID] {
+			listed = append(listed, bk)
+		} else {
+			log.Debugw("skip the book from the list which doesn't match the filter",
+				"book_id", bk.ID, "title", bk.Title, "filter", s.expr.String())
+		}
+	}
+
+	return listed
+}
@@ -0,0 +1,37 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/xorcare/miflib.go/internal/book"
+	"github.com/xorcare/miflib.go/internal/booklist"
+	"github.com/xorcare/miflib.go/internal/filter"
+)
+
+func TestSelection_books(t *testing.T) {
+	expr, err := filter.Parse("id:-100")
+	require.NoError(t, err)
+	list, err := booklist.Parse([]byte("42\n120\n404\n"))
+	require.NoError(t, err)
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	s := selection{expr: expr, list: list}
+	books := s.books([]book.Book{{ID: 42}, {ID: 50}, {ID: 120}}, zap.New(core).Sugar())
+
+	require.Equal(t, []book.Book{{ID: 42}}, books)
+
+	// only the book missing in the whole catalog is reported, the book
+	// excluded by the filter is not.
+	missing := logs.FilterMessage("the book from the list is not found in the catalog").All()
+	require.Len(t, missing, 1)
+	require.EqualValues(t, 404, missing[0].ContextMap()["book_id"])
+}
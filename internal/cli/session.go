@@ -0,0 +1,135 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/net/publicsuffix"
+
+	"github.com/xorcare/miflib.go/internal/api"
+	"github.com/xorcare/miflib.go/internal/flag"
+	"github.com/xorcare/miflib.go/internal/logging"
+	"github.com/xorcare/miflib.go/internal/secret"
+)
+
+// connect creates the client of the library and logs in with the
+// credentials.
+func connect(ctx context.Context, c *cli.Context, logs *logging.Logger, username, password string) (*api.Client, error) {
+	jar, err := cookiejar.New(
+		&cookiejar.Options{
+			PublicSuffixList: publicsuffix.List,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	apiClient := api.NewClient(
+		"https://"+c.String(flag.Hostname.Name),
+		logs.Named(logging.API).Sugar(),
+		api.OptDoer(
+			&http.Client{
+				Timeout: c.Duration(flag.HTTPTimeout.Name),
+				Transport: &http.Transport{
+					ResponseHeaderTimeout: c.Duration(flag.HTTPResponseHeaderTimeout.Name),
+				},
+				Jar: jar,
+			},
+		),
+	)
+
+	if err := apiClient.Login(ctx, username, password); err != nil {
+		return nil, err
+	}
+
+	return apiClient, nil
+
+}
+
+// credentials returns the username and the password for the library, the
+// password is taken from the first of the sources: the flags, the .netrc
+// entry of the host or the prompt in the terminal. The password flags
+// follow the precedence of the command line, the environment and the
+// profile.
+func credentials(ctx context.Context, c *cli.Context) (username, password string, err error) {
+	if err := requireFlags(c, flag.Hostname.Name); err != nil {
+		return "", "", err
+	}
+
+	username = c.String(flag.Username.Name)
+
+	// the password is taken from the source of the highest precedence, only
+	// the sources of the same precedence conflict.
+	var sources []string
+	rank := 0
+	for _, name := range []string{
+		flag.Password.Name, flag.PasswordFile.Name, flag.PasswordStdin.Name, flag.PasswordCommand.Name,
+	} {
+		source := flagSource(c, name)
+		if source == sourceDefault || name == flag.PasswordStdin.Name && !c.Bool(name) {
+			continue
+		}
+		switch r := sourceRanks[source]; {
+		case r > rank:
+			sources, rank = []string{name}, r
+		case r == rank:
+			sources = append(sources, name)
+		}
+	}
+	if len(sources) > 1 {
+		return "", "", fmt.Errorf(`only one of the flags "%s" can be set by the %s`,
+			strings.Join(sources, `", "`), flagSource(c, sources[0]))
+	}
+
+	if len(sources) == 1 {
+		switch sources[0] {
+		case flag.Password.Name:
+			password = c.String(flag.Password.Name)
+		case flag.PasswordFile.Name:
+			password, err = secret.FromFile(c.String(flag.PasswordFile.Name))
+		case flag.PasswordStdin.Name:
+			password, err = secret.FromReader(os.Stdin)
+		case flag.PasswordCommand.Name:
+			password, err = secret.FromCommand(ctx, c.String(flag.PasswordCommand.Name))
+		}
+		if err != nil {
+			return "", "", fmt.Errorf("the password of the flag %q: %v", sources[0], err)
+		}
+	}
+
+	if password == "" {
+		entry, ok, err := secret.LookupNetrc(secret.NetrcPath(), c.String(flag.Hostname.Name))
+		if err != nil {
+			return "", "", err
+		}
+		if ok && (username == "" || username == entry.Login) {
+			username, password = entry.Login, entry.Password
+		}
+	}
+
+	if username == "" {
+		return "", "", fmt.Errorf("required flag %q not set", flag.Username.Name)
+	}
+
+	if password == "" && secret.IsTerminal(os.Stdin) {
+		prompt := fmt.Sprintf("Password for %s at %s: ", username, c.String(flag.Hostname.Name))
+		if password, err = secret.Prompt(os.Stdin, os.Stderr, prompt); err != nil {
+			return "", "", err
+		}
+	}
+
+	if password == "" {
+		return "", "", fmt.Errorf("required flag %q not set", flag.Password.Name)
+	}
+
+	return username, password, nil
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v2"
+
+	"github.com/xorcare/miflib.go/internal/flag"
+)
+
+func TestCredentials(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	defer os.Setenv("XDG_CONFIG_HOME", os.Getenv("XDG_CONFIG_HOME"))
+	require.NoError(t, os.Setenv("XDG_CONFIG_HOME", tempDir))
+	// the environment changes the flags which are shared by the apps.
+	saved := *flag.Password
+	defer func() { *flag.Password = saved }()
+
+	config := filepath.Join(tempDir, "config.yaml")
+	require.NoError(t, ioutil.WriteFile(config, []byte(`
+profiles:
+  command:
+    password-command: echo profile
+  conflict:
+    password-command: echo profile
+    password-file: /dev/null
+`), 0644))
+
+	run := func(env string, args ...string) (string, error) {
+		if env == "" {
+			require.NoError(t, os.Unsetenv("MIFLIB_PASSWORD"))
+		} else {
+			require.NoError(t, os.Setenv("MIFLIB_PASSWORD", env))
+			defer os.Unsetenv("MIFLIB_PASSWORD")
+		}
+
+		var password string
+		app := New("test")
+		app.Commands = append(app.Commands, &cli.Command{
+			Name:   "probe",
+			Before: inheritFlags,
+			Flags:  connectionFlags,
+			Action: func(c *cli.Context) (err error) {
+				_, password, err = credentials(context.Background(), c)
+				return err
+			},
+		})
+		args = append([]string{"miflib", "-h", "example.com", "-u", "user", "--config", config}, args...)
+		err := app.Run(args)
+		return password, err
+	}
+
+	password, err := run("", "--profile", "command", "probe")
+	require.NoError(t, err)
+	require.Equal(t, "profile", password)
+
+	password, err = run("", "--profile", "command", "--password", "flag", "probe")
+	require.NoError(t, err)
+	require.Equal(t, "flag", password)
+
+	_, err = run("", "--password", "flag", "--password-command", "echo flag", "probe")
+	require.EqualError(t, err, `only one of the flags "password", "password-command" can be set by the flag`)
+
+	_, err = run("", "--profile", "conflict", "probe")
+	require.EqualError(t, err, `only one of the flags "password-file", "password-command" can be set by the profile`)
+
+	password, err = run("", "--profile", "command", "probe", "--password", "after")
+	require.NoError(t, err)
+	require.Equal(t, "after", password)
+
+	_, err = run("", "--password", "flag", "probe", "--password-command", "echo after")
+	require.EqualError(t, err, `only one of the flags "password", "password-command" can be set by the flag`)
+
+	password, err = run("env", "--profile", "command", "probe")
+	require.NoError(t, err)
+	require.Equal(t, "env", password)
+}
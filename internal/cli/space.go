@@ -0,0 +1,85 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+	"go.uber.org/zap"
+
+	"github.com/xorcare/miflib.go/internal/book"
+	"github.com/xorcare/miflib.go/internal/bytesize"
+	"github.com/xorcare/miflib.go/internal/downloader"
+	"github.com/xorcare/miflib.go/internal/flag"
+	"github.com/xorcare/miflib.go/internal/osutil"
+)
+
+// The actions of the check of the free space before the start.
+const (
+	spaceCheckFail = "fail"
+	spaceCheckWarn = "warn"
+	spaceCheckOff  = "off"
+)
+
+// spaceReserve returns the reserve of the free space and the action of the
+// check configured by the flags.
+func spaceReserve(c *cli.Context) (reserve uint64, check string, err error) {
+	reserve, err = bytesize.Parse(c.String(flag.MinFreeSpace.Name))
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid value of the flag %q: %v", flag.MinFreeSpace.Name, err)
+	}
+
+	switch check = c.String(flag.SpaceCheck.Name); check {
+	case spaceCheckFail, spaceCheckWarn, spaceCheckOff:
+	default:
+		return 0, "", fmt.Errorf("invalid value of the flag %q: %q, expected one of: %s, %s, %s",
+			flag.SpaceCheck.Name, check, spaceCheckFail, spaceCheckWarn, spaceCheckOff)
+	}
+
+	return reserve, check, nil
+}
+
+// checkSpace estimates the size of the download of the books limited by
+// the quota and compares it with the free space of the directory.
+func checkSpace(ctx context.Context, loader *downloader.Loader, dir string, reserve uint64, check string,
+	quota *downloader.Quota, bks []book.Book, log *zap.SugaredLogger) error {
+	if check == spaceCheckOff {
+		return nil
+	}
+
+	plan, err := loader.Estimate(ctx, bks)
+	if err != nil {
+		return err
+	}
+	required := uint64(plan.Bytes())
+	if quota != nil && required > quota.Limit() {
+		required = quota.Limit()
+	}
+
+	free, err := osutil.FreeSpace(dir)
+	if err == osutil.ErrFreeSpaceUnsupported {
+		log.Warnw("skip the check of the free space", "path", dir, "error", err)
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	log.Infow("the size of the download is estimated", "path", dir, "bytes", required, "free", free)
+
+	if required+reserve <= free {
+		return nil
+	}
+
+	err = fmt.Errorf("not enough free space in %q: %s are required to download and %s to reserve, %s are available",
+		dir, bytesize.Format(required), bytesize.Format(reserve), bytesize.Format(free))
+	if check == spaceCheckWarn {
+		log.Warnw("not enough free space", "path", dir, "bytes", required, "reserve", reserve, "free", free)
+		return nil
+	}
+
+	return err
+}
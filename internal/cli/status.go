@@ -0,0 +1,140 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/xorcare/miflib.go/internal/bytesize"
+	"github.com/xorcare/miflib.go/internal/flag"
+	"github.com/xorcare/miflib.go/internal/library"
+	"github.com/xorcare/miflib.go/internal/logging"
+)
+
+func statusCommand() *cli.Command {
+	return &cli.Command{
+		Name: "status",
+		Usage: "compares the books of the --directory with the catalog, the new books are selected" +
+			" by the same flags as the sync",
+		Action: statusAction,
+		Before: inheritFlags,
+		Flags:  commandFlags(connectionFlags, logFlags, []cli.Flag{flag.Directory}, selectionFlags),
+	}
+}
+
+func statusAction(c *cli.Context) error {
+	sel, err := newSelection(c)
+	if err != nil {
+		return err
+	}
+
+	username, password, err := credentials(context.Background(), c)
+	if err != nil {
+		return err
+	}
+
+	logs, err := newLogger(c)
+	if err != nil {
+		return err
+	}
+	defer logs.Close()
+	sugar := logs.Named(logging.CLI).Sugar()
+
+	root := c.String(flag.Directory.Name)
+	entries, err := library.Scan(root)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	ctx := context.Background()
+	client, err := connect(ctx, c, logs, username, password)
+	if err != nil {
+		return err
+	}
+
+	bks, err := client.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	statuses, err := library.Compare(entries, bks.Books)
+	if err != nil {
+		return err
+	}
+
+	// the books missing in the library are reported only if they would be
+	// downloaded by the sync.
+	wanted := make(map[int]bool, len(bks.Books))
+	for _, bk := range ignored(sel.rules, sel.books(bks.Books, sugar), sugar) {
+		wanted[bk.ID] = true
+	}
+	reported := statuses[:0]
+	for _, st := range statuses {
+		if st.State != library.StateNew || wanted[st.ID] {
+			reported = append(reported, st)
+		}
+	}
+
+	return writeStatus(c.App.Writer, root, reported)
+}
+
+// writeStatus prints the books which differ from the catalog and the
+// summary by the states, the complete books are only counted.
+func writeStatus(w io.Writer, root string, statuses []library.Status) error {
+	type summary struct {
+		books         int
+		remote, local uint64
+	}
+	summaries := make(map[library.State]*summary, len(library.States))
+	for _, state := range library.States {
+		summaries[state] = &summary{}
+	}
+
+	listed := false
+	for _, st := range statuses {
+		s := summaries[st.State]
+		s.books++
+		s.remote += st.Remote
+		s.local += st.Local
+
+		if st.State == library.StateComplete {
+			continue
+		}
+		listed = true
+		if st.Path == "" {
+			fmt.Fprintf(w, "%-8s %d %q\n", st.State, st.ID, st.Title)
+			continue
+		}
+		if rel, err := filepath.Rel(root, st.Path); err == nil {
+			st.Path = filepath.ToSlash(rel)
+		}
+		fmt.Fprintf(w, "%-8s %d %q in %q\n", st.State, st.ID, st.Title, st.Path)
+	}
+
+	if listed {
+		fmt.Fprintln(w)
+	}
+
+	var total summary
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STATE\tBOOKS\tCATALOG\tLOCAL")
+	for _, state := range library.States {
+		s := summaries[state]
+		total.books += s.books
+		total.remote += s.remote
+		total.local += s.local
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", state, s.books, bytesize.Format(s.remote), bytesize.Format(s.local))
+	}
+	fmt.Fprintf(tw, "total\t%d\t%s\t%s\n", total.books, bytesize.Format(total.remote), bytesize.Format(total.local))
+
+	return tw.Flush()
+}
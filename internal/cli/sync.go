@@ -0,0 +1,193 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/xorcare/miflib.go/internal/book"
+	"github.com/xorcare/miflib.go/internal/downloader"
+	"github.com/xorcare/miflib.go/internal/flag"
+	"github.com/xorcare/miflib.go/internal/logging"
+)
+
+func syncCommand() *cli.Command {
+	return &cli.Command{
+		Name: "sync",
+		Usage: "downloads the books of the catalog into the --directory, it's the default command" +
+			" which is run without arguments",
+		Action: syncAction,
+		Before: inheritFlags,
+		Flags:  commandFlags(connectionFlags, logFlags, layoutFlags, selectionFlags, downloadFlags),
+	}
+}
+
+func syncAction(c *cli.Context) error {
+	username, password, err := credentials(context.Background(), c)
+	if err != nil {
+		return err
+	}
+
+	layout, err := newLayout(c, flag.DirTemplate, flag.FileTemplate, flag.Filesystem, flag.Translit)
+	if err != nil {
+		return err
+	}
+
+	opts, err := loaderOptions(c)
+	if err != nil {
+		return err
+	}
+
+	sel, err := newSelection(c)
+	if err != nil {
+		return err
+	}
+	if sel.list == nil && c.Bool(flag.Prune.Name) {
+		return fmt.Errorf("the flag %q requires the flag %q", flag.Prune.Name, flag.ListFile.Name)
+	}
+	opts = append(opts, downloader.OptIgnore(sel.rules))
+
+	reserve, check, err := spaceReserve(c)
+	if err != nil {
+		return err
+	}
+
+	quota, err := newQuota(c)
+	if err != nil {
+		return err
+	}
+	opts = append(opts, downloader.OptQuota(quota))
+
+	var plan *downloader.Plan
+	if c.Bool(flag.DryRun.Name) {
+		plan = downloader.NewPlan()
+		opts = append(opts, downloader.OptPlan(plan))
+	}
+
+	logs, err := newLogger(c)
+	if err != nil {
+		return err
+	}
+	defer logs.Close()
+	logger := logs.Named(logging.CLI)
+	sugar := logger.Sugar()
+
+	ch := make(chan book.Book)
+
+	ctx, done := context.WithCancel(context.Background())
+
+	quit := make(chan os.Signal, 1)
+
+	signal.Notify(quit, os.Interrupt)
+
+	go func() {
+		<-quit
+		logger.Info("miflib is shutting down by os interrupt signal...")
+		done()
+		// You need to completely subtract the channel for successful completion
+		// in the event of an interruption of the program.
+		for range ch {
+		}
+	}()
+	apiClient, err := connect(ctx, c, logs, username, password)
+	if err != nil {
+		return err
+	}
+
+	wg, ctx := errgroup.WithContext(ctx)
+
+	loader := downloader.NewLoader(
+		c.String(flag.Directory.Name),
+		apiClient,
+		logs.Named(logging.Downloader).Sugar(),
+		append(opts, downloader.OptLayout(layout), downloader.OptFreeSpaceReserve(reserve))...,
+	)
+	for i := 0; i < c.Int(flag.NumThreads.Name); i++ {
+		wg.Go(
+			func() error {
+				return loader.Worker(ctx, ch)
+			},
+		)
+	}
+
+	wg.Go(
+		func() error {
+			defer close(ch)
+			bks, err := apiClient.List(ctx)
+			if err != nil {
+				return err
+			}
+
+			sort.Slice(
+				bks.Books, func(i, j int) bool {
+					return bks.Books[i].ID < bks.Books[j].ID
+				},
+			)
+
+			if err := layout.Validate(bks.Books); err != nil {
+				return err
+			}
+
+			sugar.Infow("the catalog is listed", "books", bks.Total)
+
+			books := sel.books(bks.Books, sugar)
+
+			if c.Bool(flag.Prune.Name) {
+				if err := prune(c.String(flag.Directory.Name), sel.list, bks.Books, plan != nil, sugar); err != nil {
+					return err
+				}
+			}
+
+			if plan == nil {
+				// the ignored books are skipped by the loader in the plan.
+				books = ignored(sel.rules, books, sugar)
+
+				err := checkSpace(ctx, &loader, c.String(flag.Directory.Name), reserve, check, quota, books, sugar)
+				if err != nil {
+					return err
+				}
+			}
+
+			for i, bk := range books {
+				if quota.Exceeded() {
+					// the books are not scheduled after the quota is reached.
+					quota.Defer(books[i:]...)
+					break
+				}
+
+				sugar.Infow("the books are waiting to be downloaded", "books", len(books)-i)
+
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case ch <- bk:
+				}
+			}
+
+			return nil
+		},
+	)
+
+	if err := wg.Wait(); err != nil {
+		return err
+	}
+
+	if plan != nil {
+		return plan.Write(c.App.Writer, c.String(flag.Directory.Name))
+	}
+
+	reportQuota(quota, sugar)
+
+	logger.Info("correct completion of downloading")
+
+	return nil
+}
@@ -0,0 +1,144 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/xorcare/miflib.go/internal/downloader"
+	"github.com/xorcare/miflib.go/internal/flag"
+	"github.com/xorcare/miflib.go/internal/library"
+	"github.com/xorcare/miflib.go/internal/logging"
+)
+
+func verifyCommand() *cli.Command {
+	return &cli.Command{
+		Name: "verify",
+		Usage: "checks the files of the downloaded books against their checksums and the sizes" +
+			" from the catalog, all books of the --directory are checked if no book id is given",
+		ArgsUsage: "[book id...]",
+		Description: "The checksums are recorded to the manifest of the book when it's downloaded." +
+			" The sync records the manifests of the books downloaded earlier from their current" +
+			" contents, so the damage which happened before can't be found by the checksums," +
+			" only the sizes of the files are still checked against the catalog.",
+		Before: inheritFlags,
+		Action: verifyAction,
+		Flags: commandFlags(
+			connectionFlags, logFlags, layoutFlags,
+			[]cli.Flag{flag.MinFreeSpace, flag.Repair},
+		),
+	}
+}
+
+func verifyAction(c *cli.Context) error {
+	ids := make(map[int]bool, c.NArg())
+	for _, arg := range c.Args().Slice() {
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			return fmt.Errorf("invalid book id %q: %v", arg, err)
+		}
+		ids[id] = true
+	}
+
+	layout, err := newLayout(c, flag.DirTemplate, flag.FileTemplate, flag.Filesystem, flag.Translit)
+	if err != nil {
+		return err
+	}
+
+	reserve, _, err := spaceReserve(c)
+	if err != nil {
+		return err
+	}
+
+	repair := c.Bool(flag.Repair.Name)
+	var username, password string
+	if repair {
+		if username, password, err = credentials(context.Background(), c); err != nil {
+			return err
+		}
+	}
+
+	logs, err := newLogger(c)
+	if err != nil {
+		return err
+	}
+	defer logs.Close()
+
+	root := c.String(flag.Directory.Name)
+	entries, err := library.Scan(root)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	var client downloader.Downloader
+	if repair {
+		if client, err = connect(ctx, c, logs, username, password); err != nil {
+			return err
+		}
+	}
+
+	loader := downloader.NewLoader(
+		root,
+		client,
+		logs.Named(logging.Downloader).Sugar(),
+		downloader.OptLayout(layout),
+		downloader.OptFreeSpaceReserve(reserve),
+	)
+
+	var books, files, damaged, repaired, unverified int
+	w := c.App.Writer
+	for _, entry := range entries {
+		if len(ids) > 0 && !ids[entry.Book.ID] || !entry.Downloaded {
+			continue
+		}
+
+		v, err := loader.Verify(ctx, entry, repair)
+		if err != nil {
+			return err
+		}
+
+		rel := entry.Path
+		if r, err := filepath.Rel(root, entry.Path); err == nil {
+			rel = filepath.ToSlash(r)
+		}
+		if v.NoManifest {
+			unverified++
+			fmt.Fprintf(w, "%-8s %d %q in %q: no manifest\n", "skipped", v.ID, v.Title, rel)
+			continue
+		}
+
+		books++
+		files += v.Files
+		if len(v.Damages) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(w, "%-8s %d %q in %q\n", "damaged", v.ID, v.Title, rel)
+		for _, d := range v.Damages {
+			damaged++
+			note := ""
+			if d.Repaired {
+				repaired++
+				note = ", repaired"
+			}
+			fmt.Fprintf(w, "  %-13s %s%s\n", d.Problem, d.Path, note)
+		}
+	}
+
+	fmt.Fprintf(w, "total: %d books, %d files verified, %d damaged, %d repaired, %d books without manifest\n",
+		books, files, damaged, repaired, unverified)
+
+	if damaged > repaired {
+		return fmt.Errorf("%d damaged files are found", damaged-repaired)
+	}
+
+	return nil
+}
@@ -0,0 +1,175 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package config reads the configuration file of the application with the
+// named profiles. Every profile sets the values of the global flags, the
+// keys are the names of the flags, for example:
+//
+//	# the profile used if no profile is selected
+//	profile: personal
+//	profiles:
+//	  personal:
+//	    username: user@example.com
+//	    directory: /home/user/miflib
+//	    include: ebook,audiobook
+//	  team-share:
+//	    directory: /mnt/share/miflib
+//	    filesystem: windows
+//	    filter: "badge:new"
+//	    num-threads: 2
+//
+// The values are scalars or lists of scalars, the lists are joined by
+// commas for the flags which take one value.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// FileName it's the name of the configuration file in the configuration
+// directory of the user.
+const FileName = "miflib/config.yaml"
+
+// File it's the configuration file.
+type File struct {
+	// Profile is the name of the profile used if no profile is selected.
+	Profile  string             `yaml:"profile"`
+	Profiles map[string]Profile `yaml:"profiles"`
+}
+
+// Profile it's the values of the flags by the names of the flags.
+type Profile map[string]interface{}
+
+// DefaultPath returns the path of the configuration file in the
+// configuration directory of the user, it's empty if the directory is
+// unknown.
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, filepath.FromSlash(FileName))
+}
+
+// Read reads the configuration file.
+func Read(filename string) (File, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return File{}, err
+	}
+
+	file, err := Parse(data)
+	if err != nil {
+		return File{}, fmt.Errorf("config: %s: %v", filename, err)
+	}
+
+	return file, nil
+}
+
+// Parse parses the configuration file.
+func Parse(data []byte) (File, error) {
+	var file File
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return File{}, err
+	}
+
+	if file.Profile != "" {
+		if _, ok := file.Profiles[file.Profile]; !ok {
+			return File{}, fmt.Errorf("the default profile %q is not defined", file.Profile)
+		}
+	}
+
+	for name, profile := range file.Profiles {
+		if _, err := profile.Values(); err != nil {
+			return File{}, fmt.Errorf("profile %q: %v", name, err)
+		}
+	}
+
+	return file, nil
+}
+
+// Lookup returns the profile by the name, the default profile of the file
+// is used for the empty name, the nil profile is returned if there is no
+// default profile.
+func (f File) Lookup(name string) (Profile, error) {
+	if name == "" {
+		name = f.Profile
+	}
+	if name == "" {
+		return nil, nil
+	}
+
+	profile, ok := f.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("config: unknown profile %q, expected one of: %s", name, f.names())
+	}
+
+	return profile, nil
+}
+
+func (f File) names() string {
+	names := make([]string, 0, len(f.Profiles))
+	for name := range f.Profiles {
+		names = append(names, strconv.Quote(name))
+	}
+	sort.Strings(names)
+
+	if len(names) == 0 {
+		return "no profiles are defined"
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// Values returns the values of the flags converted to strings, the scalar
+// value is a list of one element.
+func (p Profile) Values() (map[string][]string, error) {
+	values := make(map[string][]string, len(p))
+	for key, value := range p {
+		if list, ok := value.([]interface{}); ok {
+			for _, item := range list {
+				s, err := scalar(item)
+				if err != nil {
+					return nil, fmt.Errorf("the value of %q: %v", key, err)
+				}
+				values[key] = append(values[key], s)
+			}
+			continue
+		}
+
+		s, err := scalar(value)
+		if err != nil {
+			return nil, fmt.Errorf("the value of %q: %v", key, err)
+		}
+		values[key] = []string{s}
+	}
+
+	return values, nil
+}
+
+func scalar(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case nil:
+		return "", nil
+	default:
+		return "", errors.New("expected a scalar or a list of scalars")
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	file, err := Parse([]byte(`
+profile: personal
+profiles:
+  personal:
+    username: user@example.com
+    num-threads: 2
+    prune: true
+    min-free-space: 1.5
+  team-share:
+    directory: /mnt/share/miflib
+    include: [ebook, audiobook]
+    filter:
+`))
+	require.NoError(t, err)
+
+	profile, err := file.Lookup("")
+	require.NoError(t, err)
+	values, err := profile.Values()
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{
+		"username":       {"user@example.com"},
+		"num-threads":    {"2"},
+		"prune":          {"true"},
+		"min-free-space": {"1.5"},
+	}, values)
+
+	profile, err = file.Lookup("team-share")
+	require.NoError(t, err)
+	values, err = profile.Values()
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{
+		"directory": {"/mnt/share/miflib"},
+		"include":   {"ebook", "audiobook"},
+		"filter":    {""},
+	}, values)
+
+	_, err = file.Lookup("test-server")
+	require.EqualError(t, err, `config: unknown profile "test-server", expected one of: "personal", "team-share"`)
+
+	profile, err = File{}.Lookup("")
+	require.NoError(t, err)
+	require.Nil(t, profile)
+}
+
+func TestParse_error(t *testing.T) {
+	tests := map[string]string{
+		"profile: work\n":                         `the default profile "work" is not defined`,
+		"profiles:\n  work:\n    include: {a: b}": `profile "work": the value of "include": expected a scalar or a list of scalars`,
+		"profiles:\n  work:\n    include: [[a]]":  `profile "work": the value of "include": expected a scalar or a list of scalars`,
+		"profils: {}":                             "yaml: unmarshal errors:\n  line 1: field profils not found in type config.File",
+	}
+	for data, want := range tests {
+		t.Run(data, func(t *testing.T) {
+			_, err := Parse([]byte(data))
+			require.EqualError(t, err, want)
+		})
+	}
+
+	_, err := Read("testdata/not-exist.yaml")
+	require.Error(t, err)
+}
@@ -0,0 +1,187 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package downloader
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/xorcare/miflib.go/internal/book"
+	"github.com/xorcare/miflib.go/internal/library"
+	"github.com/xorcare/miflib.go/internal/osutil"
+)
+
+// authorsDir it's the directory of the book with the portraits of its
+// authors.
+const authorsDir = "authors"
+
+// photos it's the portraits of the authors downloaded by the loader, the
+// portrait of an author is downloaded once even if the books of the author
+// are processed in parallel.
+type photos struct {
+	mu   sync.Mutex
+	done map[string]*photo
+}
+
+type photo struct {
+	once sync.Once
+	err  error
+}
+
+// download calls the function once for the filename.
+func (p *photos) download(filename string, f func() error) error {
+	if p == nil {
+		return f()
+	}
+
+	p.mu.Lock()
+	ph, ok := p.done[filename]
+	if !ok {
+		ph = &photo{}
+		p.done[filename] = ph
+	}
+	p.mu.Unlock()
+
+	ph.once.Do(func() {
+		ph.err = f()
+	})
+
+	return ph.err
+}
+
+// downloadAuthorPhotos downloads the portraits of the authors into the
+// AuthorsDir of the library and links them into the directory of the book,
+// so the portrait of an author is stored once for all books. The portrait
+// which is already in the AuthorsDir is not downloaded again.
+func (l *Loader) downloadAuthorPhotos(ctx context.Context, basepath string, book book.Book) error {
+	l.log.Infow("start downloading the author photos", "book_id", book.ID, "title", book.Title)
+	defer l.log.Infow("finish downloading the author photos", "book_id", book.ID, "title", book.Title)
+	for _, author := range book.Authors {
+		name := l.photoName(author)
+		if name == "" {
+			continue
+		}
+
+		shared := l.filePath(filepath.Join(l.root, library.AuthorsDir, name))
+		link := l.filePath(filepath.Join(basepath, authorsDir, name))
+		err := l.photos.download(shared, func() error {
+			if l.plan != nil {
+				return l.planFile(link, shared, author.Photo, 0)
+			}
+			if exist, err := osutil.FileExists(shared); err != nil || exist {
+				return err
+			}
+			return l.downloadShared(ctx, author.Photo, shared)
+		})
+		if err != nil {
+			return err
+		}
+		if l.plan != nil {
+			continue
+		}
+
+		if exist, err := osutil.FileExists(shared); err != nil {
+			return err
+		} else if !exist {
+			continue
+		}
+
+		if err := linkFile(shared, link); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// downloadShared downloads the shared file into the temporary file and
+// renames it to its name only after the download succeeds, so the file
+// cut short by the interrupted run is never linked into the books.
+func (l *Loader) downloadShared(ctx context.Context, fileURL, filename string) error {
+	tmp := partFile(filename)
+	defer os.Remove(tmp)
+
+	if err := l.downloadFile(ctx, fileURL, tmp); err != nil {
+		return err
+	}
+
+	if exist, err := osutil.FileExists(tmp); err != nil || !exist {
+		// the missing file is skipped by the download.
+		return err
+	}
+
+	return os.Rename(tmp, filename)
+}
+
+// photoName returns the file name of the portrait of the author, the hash
+// of the URL of the portrait tells apart the authors with the same name.
+// The name is empty if the author has no portrait.
+func (l *Loader) photoName(author book.Author) string {
+	name := l.layout.profile.clean(author.Name)
+	if author.Photo == "" || name == "" {
+		return ""
+	}
+
+	sum := sha1.Sum([]byte(author.Photo))
+	name += " " + hex.EncodeToString(sum[:])[:8] + urlExt(author.Photo)
+
+	lim := l.layout.profile.rules().limits
+	return lim.fit("", name, 0)
+}
+
+// linkFile creates the hard link to the file, the file is copied if the
+// file system doesn't support hard links.
+func linkFile(oldname, newname string) error {
+	oldinfo, err := os.Stat(oldname)
+	if err != nil {
+		return err
+	}
+
+	if info, err := os.Stat(newname); err == nil {
+		if os.SameFile(oldinfo, info) {
+			return nil
+		}
+		if err := os.Remove(newname); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(newname), 0755); err != nil {
+		return err
+	}
+
+	if err := os.Link(oldname, newname); err == nil {
+		return nil
+	}
+
+	return copyFile(oldname, newname)
+}
+
+func copyFile(oldname, newname string) error {
+	src, err := os.Open(oldname)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(newname)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+
+	return dst.Close()
+}
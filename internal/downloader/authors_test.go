@@ -0,0 +1,117 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package downloader
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/xorcare/miflib.go/internal/api"
+	"github.com/xorcare/miflib.go/internal/book"
+	"github.com/xorcare/miflib.go/internal/library"
+)
+
+func TestLoader_downloadAuthorPhotos(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	amk := new(apiMock)
+	amk.Test(t)
+	defer amk.AssertExpectations(t)
+
+	l := NewLoader(tempDir, amk, zap.NewNop().Sugar())
+	ctx := context.Background()
+
+	shared := filepath.Join(tempDir, library.AuthorsDir, "Максим Дорофеев 7c2272a3.jpg")
+	amk.On("DownloadFile", ctx, "https://authors/1.jpg?v=2", partFile(shared)).Return(nil).Once().
+		Run(func(args mock.Arguments) {
+			writeFile(t, args.String(2), 10)
+		})
+	namesake := filepath.Join(tempDir, library.AuthorsDir, "Максим Дорофеев 9d43315d.jpg")
+	amk.On("DownloadFile", ctx, "https://authors/3.jpg", partFile(namesake)).Return(nil).Once().
+		Run(func(args mock.Arguments) {
+			writeFile(t, args.String(2), 20)
+		})
+	amk.On("DownloadFile", ctx, "https://authors/2.jpg", partFile(filepath.Join(tempDir, library.AuthorsDir, "Нет фото eba6b967.jpg"))).
+		Return(&api.Error{Code: 404}).Once()
+
+	authors := []book.Author{
+		{Name: "Максим Дорофеев", Photo: "https://authors/1.jpg?v=2"},
+		{Name: "Нет фото", Photo: "https://authors/2.jpg"},
+		{Name: "Без ссылки"},
+		{Name: "Максим Дорофеев", Photo: "https://authors/3.jpg"},
+	}
+
+	jedi := filepath.Join(tempDir, "jedi")
+	require.NoError(t, l.downloadAuthorPhotos(ctx, jedi, book.Book{Authors: authors}))
+	fear := filepath.Join(tempDir, "fear")
+	require.NoError(t, l.downloadAuthorPhotos(ctx, fear, book.Book{Authors: authors[:1]}))
+
+	// the next run finds the portrait in the library and doesn't download it.
+	next := NewLoader(tempDir, amk, zap.NewNop().Sugar())
+	path := filepath.Join(tempDir, "path")
+	require.NoError(t, next.downloadAuthorPhotos(ctx, path, book.Book{Authors: authors[:1]}))
+
+	info, err := os.Stat(shared)
+	require.NoError(t, err)
+	for _, bookpath := range []string{jedi, fear, path} {
+		linked, err := os.Stat(filepath.Join(bookpath, "authors", filepath.Base(shared)))
+		require.NoError(t, err)
+		require.True(t, os.SameFile(info, linked))
+	}
+	require.FileExists(t, filepath.Join(jedi, "authors", filepath.Base(namesake)))
+	require.NoFileExists(t, filepath.Join(jedi, "authors", "Нет фото eba6b967.jpg"))
+
+	entries, err := library.Scan(tempDir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	t.Run("interrupted", func(t *testing.T) {
+		author := book.Author{Name: "Прерванный", Photo: "https://authors/4.jpg"}
+		shared := filepath.Join(tempDir, library.AuthorsDir, l.photoName(author))
+		amk.On("DownloadFile", ctx, "https://authors/4.jpg", partFile(shared)).Return(io.ErrUnexpectedEOF).Once().
+			Run(func(args mock.Arguments) {
+				writeFile(t, args.String(2), 5)
+			})
+		amk.On("DownloadFile", ctx, "https://authors/4.jpg", partFile(shared)).Return(nil).Once().
+			Run(func(args mock.Arguments) {
+				writeFile(t, args.String(2), 10)
+			})
+
+		bk := book.Book{Authors: []book.Author{author}}
+		first := NewLoader(tempDir, amk, zap.NewNop().Sugar())
+		require.Error(t, first.downloadAuthorPhotos(ctx, jedi, bk))
+		require.NoFileExists(t, shared)
+
+		// the next run downloads the portrait cut short again.
+		next := NewLoader(tempDir, amk, zap.NewNop().Sugar())
+		require.NoError(t, next.downloadAuthorPhotos(ctx, jedi, bk))
+		info, err := os.Stat(shared)
+		require.NoError(t, err)
+		require.EqualValues(t, 10, info.Size())
+		require.NoFileExists(t, partFile(shared))
+	})
+}
+
+func TestLoader_photoName(t *testing.T) {
+	l := NewLoader("", nil, zap.NewNop().Sugar())
+
+	require.Empty(t, l.photoName(book.Author{Name: "Без ссылки"}))
+	require.Empty(t, l.photoName(book.Author{Name: "?", Photo: "https://authors/1.jpg"}))
+
+	name := l.photoName(book.Author{Name: strings.Repeat("Дорофеев", 40), Photo: "https://authors/1.jpg"})
+	require.LessOrEqual(t, len(name), posixLimits.name)
+	require.Equal(t, ".jpg", filepath.Ext(name))
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/xorcare/miflib.go/internal/book"
+)
+
+// Category it's a kind of the materials of the book.
+type Category string
+
+// Categories of the materials of the book.
+const (
+	// CategoryEbook it's the files of the ebook.
+	CategoryEbook Category = "ebook"
+	// CategoryAudiobook it's the files of the audiobook.
+	CategoryAudiobook Category = "audiobook"
+	// CategoryCover it's the images of the book cover.
+	CategoryCover Category = "cover"
+	// CategoryDemo it's the demo materials of the book.
+	CategoryDemo Category = "demo"
+	// CategoryPhotos it's the photos of the book.
+	CategoryPhotos Category = "photos"
+	// CategoryVideos it's the videos about the book.
+	CategoryVideos Category = "videos"
+	// CategoryAuthorPhotos it's the portraits of the authors of the book.
+	CategoryAuthorPhotos Category = "author-photos"
+)
+
+// AllCategories it's the list of all supported categories, the special
+// name "all" can be used instead of it in ParseCategories.
+var AllCategories = []Category{
+	CategoryEbook,
+	CategoryAudiobook,
+	CategoryCover,
+	CategoryDemo,
+	CategoryPhotos,
+	CategoryVideos,
+	CategoryAuthorPhotos,
+}
+
+// DefaultCategories it's the categories downloaded by default. Demo files
+// and photos of books are mostly information garbage that accumulates like
+// snow as you download books for study, so they are disabled by default.
+var DefaultCategories = []Category{
+	CategoryEbook,
+	CategoryAudiobook,
+	CategoryCover,
+}
+
+// ParseCategories parses the names of the categories, every name may
+// contain several comma separated categories.
+func ParseCategories(names ...string) ([]Category, error) {
+	var categories []Category
+	seen := make(map[Category]bool, len(AllCategories))
+	add := func(c Category) {
+		if !seen[c] {
+			seen[c] = true
+			categories = append(categories, c)
+		}
+	}
+
+	for _, name := range names {
+		for _, field := range strings.Split(name, ",") {
+			field = strings.ToLower(strings.TrimSpace(field))
+			switch {
+			case field == "":
+			case field == "all":
+				for _, c := range AllCategories {
+					add(c)
+				}
+			case validCategory(Category(field)):
+				add(Category(field))
+			default:
+				return nil, fmt.Errorf("downloader: unknown category %q, expected one of: %s, all",
+					field, joinCategories(AllCategories))
+			}
+		}
+	}
+
+	return categories, nil
+}
+
+func validCategory(c Category) bool {
+	for _, category := range AllCategories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+func joinCategories(categories []Category) string {
+	names := make([]string, 0, len(categories))
+	for _, c := range categories {
+		names = append(names, string(c))
+	}
+	return strings.Join(names, ", ")
+}
+
+// downloaders returns the download functions of the enabled categories
+// which are not ignored for the book.
+func (l *Loader) downloaders(bk book.Book) []func(context.Context, string, book.Book) error {
+	categories := l.categories
+	if categories == nil {
+		categories = DefaultCategories
+	}
+
+	all := map[Category]func(context.Context, string, book.Book) error{
+		CategoryEbook:        l.downloadBook,
+		CategoryAudiobook:    l.downloadAudiobook,
+		CategoryCover:        l.downloadCover,
+		CategoryDemo:         l.downloadDemo,
+		CategoryPhotos:       l.downloadPhotos,
+		CategoryVideos:       l.downloadVideos,
+		CategoryAuthorPhotos: l.downloadAuthorPhotos,
+	}
+
+	downloaders := make([]func(context.Context, string, book.Book) error, 0, len(categories))
+	for _, c := range categories {
+		f, ok := all[c]
+		if !ok {
+			continue
+		}
+		if ignored, by := l.ignore.Material(bk, string(c), ""); ignored {
+			l.log.Infow("skip the ignored category", "book_id", bk.ID, "category", c, "rule", by)
+			continue
+		}
+		downloaders = append(downloaders, f)
+	}
+
+	return downloaders
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package downloader
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/xorcare/miflib.go/internal/book"
+	"github.com/xorcare/miflib.go/internal/ctxtest"
+	"github.com/xorcare/miflib.go/internal/ignore"
+)
+
+func TestParseCategories(t *testing.T) {
+	categories, err := ParseCategories("ebook, Demo", "videos,ebook", "")
+	require.NoError(t, err)
+	require.Equal(t, []Category{CategoryEbook, CategoryDemo, CategoryVideos}, categories)
+
+	categories, err = ParseCategories("cover,all")
+	require.NoError(t, err)
+	require.Equal(t, []Category{CategoryCover, CategoryEbook, CategoryAudiobook,
+		CategoryDemo, CategoryPhotos, CategoryVideos, CategoryAuthorPhotos}, categories)
+
+	_, err = ParseCategories("ebook,e-book")
+	require.EqualError(t, err, `downloader: unknown category "e-book", expected one of:`+
+		` ebook, audiobook, cover, demo, photos, videos, author-photos, all`)
+}
+
+func TestOptCategories(t *testing.T) {
+	amk := new(apiMock)
+	amk.Test(t)
+	defer amk.AssertExpectations(t)
+
+	l := NewLoader("", amk, zap.NewNop().Sugar(), OptCategories(
+		CategoryDemo, CategoryPhotos, CategoryVideos, CategoryAuthorPhotos,
+	))
+
+	amk.On("DownloadFile", ctxtest.Match, "https://demo/epub", "jedi/demo/epub/Джедайские техники.epub").Return(nil).Once()
+	amk.On("DownloadFile", ctxtest.Match, "https://photos/1.png", "jedi/photos/1.png").Return(nil).Once()
+	amk.On("DownloadFile", ctxtest.Match, "https://authors/1.jpg", partFile(".authors/Максим Дорофеев 568b7b9e.jpg")).Return(nil).Once()
+
+	require.NoError(t, l.download(ctxtest.Background(), "jedi", book.Book{
+		Title:   "Джедайские техники",
+		Authors: []book.Author{{Name: "Максим Дорофеев", Photo: "https://authors/1.jpg"}, {Name: "Без фото"}},
+		Cover:   book.Cover{Large: "https://cover/large.png"},
+		Photos:  []book.Address{{URL: "https://photos/1.png"}},
+		Files: book.Files{
+			Books: book.Formats{"epub": {{URL: "https://epub"}}},
+			Demo:  book.Formats{"epub": {{URL: "https://demo/epub"}}},
+		},
+	}))
+
+	t.Run("none", func(t *testing.T) {
+		l := NewLoader("", amk, zap.NewNop().Sugar(), OptCategories())
+		require.NoError(t, l.download(ctxtest.Background(), "jedi", book.Book{
+			Cover: book.Cover{Large: "https://cover/large.png"},
+		}))
+	})
+}
+
+func TestOptIgnore(t *testing.T) {
+	amk := new(apiMock)
+	amk.Test(t)
+	defer amk.AssertExpectations(t)
+
+	rules, err := ignore.Parse([]byte("format:fb2\ncategory:cover\ntitle:\"Аудио*\""))
+	require.NoError(t, err)
+
+	l := NewLoader("", amk, zap.NewNop().Sugar(), OptIgnore(rules))
+
+	amk.On("DownloadFile", ctxtest.Match, "https://epub", "jedi/e-book/epub/Джедайские техники.epub").Return(nil).Once()
+
+	require.NoError(t, l.download(ctxtest.Background(), "jedi", book.Book{
+		Title: "Джедайские техники",
+		Cover: book.Cover{Large: "https://cover/large.png"},
+		Files: book.Files{
+			Books: book.Formats{
+				"epub": {{URL: "https://epub"}},
+				"fb2":  {{URL: "https://fb2"}},
+			},
+		},
+	}))
+
+	ch := make(chan book.Book, 1)
+	ch <- book.Book{ID: 1, Title: "Аудиоколлекция", Files: book.Files{
+		Books: book.Formats{"epub": {{URL: "https://audio/epub"}}},
+	}}
+	close(ch)
+	require.NoError(t, l.Worker(ctxtest.Background(), ch))
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package downloader
+
+import (
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/xorcare/miflib.go/internal/book"
+)
+
+// itemKey it's the identity of the file among the files of the book.
+type itemKey struct {
+	category string
+	format   string
+	index    int
+}
+
+func (it item) key() itemKey {
+	return itemKey{category: it.category, format: it.format, index: it.index}
+}
+
+// disambiguators it's the ways to make the colliding names unique, they
+// are tried in order until the names stop colliding.
+var disambiguators = []func(it item) string{
+	func(it item) string {
+		return strconv.Itoa(it.index + 1)
+	},
+	func(it item) string {
+		return it.category + " " + strconv.Itoa(it.index+1)
+	},
+	func(it item) string {
+		return it.category + " " + it.format + " " + strconv.Itoa(it.index+1)
+	},
+}
+
+// fileNames returns the slash separated paths of all the files of the book
+// stored by addresses relative to the book directory. The paths colliding
+// with each other are made unique by adding the part index and, if it's
+// not enough, the category and the format of the file. The paths that
+// differ only in case are considered colliding, since the library may be
+// placed on a case-insensitive file system.
+func (l Layout) fileNames(bookpath string, bk book.Book) (map[itemKey]string, error) {
+	items := addressItems(bk)
+	original := make([]string, len(items))
+	for i, it := range items {
+		name, err := l.fileName(bookpath, bk, it)
+		if err != nil {
+			return nil, err
+		}
+		original[i] = name
+	}
+
+	names := append([]string(nil), original...)
+	lim := l.profile.rules().limits
+	for _, disambiguator := range disambiguators {
+		colliding := collisions(names)
+		if len(colliding) == 0 {
+			break
+		}
+		for _, i := range colliding {
+			names[i] = lim.fit(bookpath, withSuffix(original[i], disambiguator(items[i])), lim.path)
+		}
+	}
+
+	// the last resort is the sequence number of the colliding file.
+	for colliding := collisions(names); len(colliding) > 0; colliding = collisions(names) {
+		for n, i := range colliding[1:] {
+			names[i] = lim.fit(bookpath, withSuffix(names[i], strconv.Itoa(n+2)), lim.path)
+		}
+	}
+
+	result := make(map[itemKey]string, len(items))
+	for i, it := range items {
+		result[it.key()] = names[i]
+	}
+
+	return result, nil
+}
+
+// collisions returns the indexes of the names which collide with other
+// names in ascending order.
+func collisions(names []string) []int {
+	seen := make(map[string]int, len(names))
+	for _, name := range names {
+		seen[strings.ToLower(name)]++
+	}
+
+	var colliding []int
+	for i, name := range names {
+		if seen[strings.ToLower(name)] > 1 {
+			colliding = append(colliding, i)
+		}
+	}
+
+	return colliding
+}
+
+// withSuffix adds the suffix in parentheses to the name of the file before
+// its extension.
+func withSuffix(name, suffix string) string {
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + " (" + suffix + ")" + ext
+}
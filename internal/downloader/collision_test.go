@@ -0,0 +1,78 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package downloader
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/xorcare/miflib.go/internal/book"
+)
+
+func TestLayout_fileNames(t *testing.T) {
+	bk := book.Book{
+		Title: "Джедайские техники",
+		Files: book.Files{
+			AudioBooks: book.Formats{
+				"mp3": {
+					{URL: "https://mp3/0", Title: "Глава"},
+					{URL: "https://mp3/1", Title: "глава"},
+					{URL: "https://mp3/2", Title: "Глава?"},
+					{URL: "https://mp3/3", Title: "Глава (2)"},
+					{URL: "https://mp3/4", Title: "Заключение"},
+				},
+			},
+			Books: book.Formats{
+				"epub": {{URL: "https://epub"}},
+			},
+			Demo: book.Formats{
+				"epub": {{URL: "https://demo/epub"}},
+			},
+		},
+	}
+
+	names, err := defaultLayout.fileNames("jedi", bk)
+	require.NoError(t, err)
+	require.Equal(t, map[itemKey]string{
+		{category: "audiobook", format: "mp3", index: 0}: "audiobook/mp3/Глава (1).mp3",
+		{category: "audiobook", format: "mp3", index: 1}: "audiobook/mp3/глава (audiobook 2).mp3",
+		{category: "audiobook", format: "mp3", index: 2}: "audiobook/mp3/Глава (3).mp3",
+		{category: "audiobook", format: "mp3", index: 3}: "audiobook/mp3/Глава (2) (audiobook 4).mp3",
+		{category: "audiobook", format: "mp3", index: 4}: "audiobook/mp3/Заключение.mp3",
+		{category: "e-book", format: "epub", index: 0}:   "e-book/epub/Джедайские техники.epub",
+		{category: "demo", format: "epub", index: 0}:     "demo/epub/Джедайские техники.epub",
+	}, names)
+
+	flat, err := NewLayout(DefaultDirTemplate, "{{.Title}}.{{.Format}}")
+	require.NoError(t, err)
+
+	names, err = flat.fileNames("jedi", bk)
+	require.NoError(t, err)
+	require.Equal(t, "Джедайские техники (e-book 1).epub", names[itemKey{category: "e-book", format: "epub"}])
+	require.Equal(t, "Джедайские техники (demo 1).epub", names[itemKey{category: "demo", format: "epub"}])
+	require.Equal(t, "Заключение.mp3", names[itemKey{category: "audiobook", format: "mp3", index: 4}])
+
+	t.Run("download", func(t *testing.T) {
+		amk := new(apiMock)
+		amk.Test(t)
+		defer amk.AssertExpectations(t)
+		l := &Loader{
+			api: amk,
+			log: zap.NewNop().Sugar(),
+		}
+
+		ctx := context.Background()
+		amk.On("DownloadFile", ctx, "https://mp3/0", "jedi/audiobook/mp3/Глава (1).mp3").Return(nil).Once()
+		amk.On("DownloadFile", ctx, "https://mp3/1", "jedi/audiobook/mp3/глава (audiobook 2).mp3").Return(nil).Once()
+		amk.On("DownloadFile", ctx, "https://mp3/2", "jedi/audiobook/mp3/Глава (3).mp3").Return(nil).Once()
+		amk.On("DownloadFile", ctx, "https://mp3/3", "jedi/audiobook/mp3/Глава (2) (audiobook 4).mp3").Return(nil).Once()
+		amk.On("DownloadFile", ctx, "https://mp3/4", "jedi/audiobook/mp3/Заключение.mp3").Return(nil).Once()
+
+		require.NoError(t, l.downloadAudiobook(ctx, "jedi", bk))
+	})
+}
@@ -6,25 +6,29 @@ package downloader
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"hash/crc32"
 	"net/url"
 	"os"
 	"path"
 	"path/filepath"
-	"strings"
+	"sort"
+	"strconv"
 
 	"golang.org/x/sync/errgroup"
 
 	"github.com/xorcare/miflib.go/internal/api"
 	"github.com/xorcare/miflib.go/internal/book"
+	"github.com/xorcare/miflib.go/internal/history"
+	"github.com/xorcare/miflib.go/internal/ignore"
+	"github.com/xorcare/miflib.go/internal/library"
 	"github.com/xorcare/miflib.go/internal/osutil"
 )
 
 type logger interface {
-	Infof(msg string, keysAndValues ...interface{})
-	Debugf(msg string, keysAndValues ...interface{})
-	Warnf(msg string, keysAndValues ...interface{})
+	Infow(msg string, keysAndValues ...interface{})
+	Debugw(msg string, keysAndValues ...interface{})
+	Warnw(msg string, keysAndValues ...interface{})
 }
 
 // Downloader this is the file loader interface.
@@ -35,33 +39,52 @@ type Downloader interface {
 // Loader is an implementation of a handler for loading all possible materials
 // from a book.
 type Loader struct {
-	api  Downloader
-	root string
-	log  logger
+	api    Downloader
+	root   string
+	log    logger
+	layout Layout
+	// categories is the categories of the materials to download, the
+	// DefaultCategories are used if it's nil.
+	categories []Category
+	// formats is the rules of the formats of the categories, the
+	// DefaultFormatRules are used for the missing categories.
+	formats map[Category]FormatRule
+	// photos is the portraits of the authors downloaded by the loader.
+	photos *photos
+	// ignore is the rules of the ignore file of the library.
+	ignore ignore.Rules
+	// reserve is the free space of the library in bytes below which the
+	// downloading is paused, zero disables the checks.
+	reserve uint64
+	// quota is the budget of the downloaded bytes shared by the loaders,
+	// the downloads are not limited if it's nil.
+	quota *Quota
+	// plan is the plan of the dry run, the files are recorded to it
+	// instead of downloading if it's not nil.
+	plan *Plan
 }
 
 // NewLoader creates new instance of loader.
-func NewLoader(basepath string, downloader Downloader, logger logger) Loader {
-	return Loader{
+func NewLoader(basepath string, downloader Downloader, logger logger, opts ...Option) Loader {
+	l := Loader{
 		api:  downloader,
 		root: basepath,
 		log:  logger,
+		photos: &photos{
+			done: make(map[string]*photo),
+		},
 	}
+
+	for _, opt := range opts {
+		opt(&l)
+	}
+
+	return l
 }
 
 // download starting the download mechanism.
 func (l *Loader) download(ctx context.Context, basepath string, bk book.Book) error {
-	type downloader func(context.Context, string, book.Book) error
-	var downloaders = []downloader{
-		l.downloadAudiobook,
-		l.downloadBook,
-		l.downloadCover,
-		// Demo files and photos of books are mostly information garbage that
-		// accumulates like snow as you download books for study, so I preferred
-		// to disable downloading this data.
-		// l.downloadDemo,
-		// l.downloadPhotos,
-	}
+	downloaders := l.downloaders(bk)
 
 	wg, ctx := errgroup.WithContext(ctx)
 	for i := range downloaders {
@@ -77,50 +100,71 @@ func (l *Loader) download(ctx context.Context, basepath string, bk book.Book) er
 // Worker it's a method for processing a channel with books,
 // it downloads information for all books read from the channel.
 func (l *Loader) Worker(ctx context.Context, ch <-chan book.Book) (err error) {
-	defer l.log.Debugf("worker finish him work, err: %v", err)
+	defer func() { l.log.Debugw("the worker is finished", "error", err) }()
 	for bk := range ch {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		default:
-			l.log.Infof("start downloading the book %q", bk.Title)
+			if ignored, by := l.ignore.Book(bk); ignored {
+				l.log.Infow("skip the ignored book", "book_id", bk.ID, "title", bk.Title, "rule", by)
+				l.plan.skip(bk, "", "ignored by the rule "+strconv.Quote(by))
+				continue
+			}
 
-			bookDir := fmt.Sprintf("%05d %s", bk.ID, bk.Title)
-			bookDir = clearBaseName(bookDir)
-			bookpath := path.Join(l.root, bookDir)
+			if l.quota.Exceeded() {
+				l.log.Infow("the book is left for the next run because the download quota is reached",
+					"book_id", bk.ID, "title", bk.Title)
+				l.quota.Defer(bk)
+				continue
+			}
+
+			l.log.Infow("start downloading the book", "book_id", bk.ID, "title", bk.Title)
+
+			bookpath, err := l.layout.bookPath(l.root, bk)
+			if err != nil {
+				return err
+			}
+			if l.plan != nil {
+				if err := l.planBook(ctx, bookpath, bk); err != nil {
+					return err
+				}
+				continue
+			}
 			if err := os.MkdirAll(bookpath, 0755); err != nil {
 				return err
 			}
-			bookFile := path.Join(bookpath, "book.json")
-			lockFile := path.Join(bookpath, ".downloaded")
+			lockFile := path.Join(bookpath, library.LockFile)
 
 			if exist, err := osutil.FileExists(lockFile); exist && err == nil {
-				l.log.Infof("the book %q is already downloaded earlier", bk.Title)
+				l.log.Infow("the book is already downloaded earlier", "book_id", bk.ID, "path", bookpath)
+				if err := l.recordMetadata(bookpath, bk); err != nil {
+					return err
+				}
+				if err := l.backfillManifest(bookpath, bk); err != nil {
+					return err
+				}
 				continue
 			} else if err != nil {
 				return err
 			}
 
-			if err := l.download(ctx, bookpath, bk); err != nil {
+			if err := l.download(ctx, bookpath, bk); err == errQuotaExceeded {
+				l.log.Infow("the book is left for the next run because the download quota is reached",
+					"book_id", bk.ID, "title", bk.Title)
+				l.quota.Defer(bk)
+				continue
+			} else if err != nil {
 				return err
 			}
 
-			l.log.Infof("finishing downloading the book: %q", bk.Title)
+			l.log.Infow("finish downloading the book", "book_id", bk.ID, "title", bk.Title)
 
-			{
-				file, err := os.Create(bookFile)
-				if err != nil {
-					return err
-				}
-				_ = file
-
-				encoder := json.NewEncoder(file)
-				encoder.SetIndent("", "\t")
-				if err := encoder.Encode(bk); err != nil {
-					file.Close()
-					return err
-				}
-				file.Close()
+			if err := l.recordMetadata(bookpath, bk); err != nil {
+				return err
+			}
+			if err := l.writeManifest(bookpath, bk); err != nil {
+				return err
 			}
 			{
 				file, err := os.Create(lockFile)
@@ -130,33 +174,33 @@ func (l *Loader) Worker(ctx context.Context, ch <-chan book.Book) (err error) {
 				file.Close()
 			}
 
-			l.log.Infof("the book %q is loaded", bk.Title)
+			l.log.Infow("the book is loaded", "book_id", bk.ID, "path", bookpath)
 		}
 	}
 
 	return nil
 }
 
+// recordMetadata saves the catalog entry of the book to its directory,
+// the previous version of the entry is kept in the history.
+func (l *Loader) recordMetadata(bookpath string, bk book.Book) error {
+	changed, err := history.Record(bookpath, bk)
+	if err != nil {
+		return err
+	}
+	if changed {
+		l.log.Debugw("the metadata of the book is recorded", "book_id", bk.ID, "path", bookpath)
+	}
+	return nil
+}
+
 func (l *Loader) downloadAudiobook(ctx context.Context, basepath string, book book.Book) error {
-	l.log.Infof("start downloading are audiobook for the book %q, ", book.Title)
-	l.log.Debugf("available audiobook %s", book.Files.AudioBooks)
-	defer l.log.Infof("finishing downloading are audiobook for the book %q, ", book.Title)
-	basepath = path.Join(basepath, "audiobook")
-	for key, as := range book.Files.AudioBooks {
-		// OGG and MP3 recordings are compressed for web playback of books,
-		// I prefer higher-quality recordings that are stored in a zip archive.
-		if key == "mp3" && len(book.Files.AudioBooks["zip"]) > 0 {
-			l.log.Infof("skip mp3 because zip exists for the book %q", book.Title)
-			continue
-		}
-		if key == "ogg" && len(book.Files.AudioBooks["zip"]) > 0 {
-			l.log.Infof("skip ogg because zip exists for the book %q", book.Title)
-			continue
-		}
-		for _, address := range as {
-			if err := l.downloadByAddress(ctx, basepath, key, address, book); err != nil {
-				return err
-			}
+	l.log.Infow("start downloading the audiobook", "book_id", book.ID, "title", book.Title)
+	l.log.Debugw("available audiobook", "book_id", book.ID, "formats", book.Files.AudioBooks)
+	defer l.log.Infow("finish downloading the audiobook", "book_id", book.ID, "title", book.Title)
+	for _, key := range l.selectFormats(CategoryAudiobook, book.Files.AudioBooks, book) {
+		if err := l.downloadByAddresses(ctx, basepath, "audiobook", key, book.Files.AudioBooks[key], book); err != nil {
+			return err
 		}
 	}
 
@@ -164,15 +208,12 @@ func (l *Loader) downloadAudiobook(ctx context.Context, basepath string, book bo
 }
 
 func (l *Loader) downloadBook(ctx context.Context, basepath string, book book.Book) error {
-	l.log.Infof("start downloading are ebook for the book %q, ", book.Title)
-	l.log.Debugf("available ebook %s", book.Files.Books)
-	defer l.log.Infof("finishing downloading are ebook for the book %q, ", book.Title)
-	basepath = path.Join(basepath, "e-book")
-	for key, as := range book.Files.Books {
-		for _, address := range as {
-			if err := l.downloadByAddress(ctx, basepath, key, address, book); err != nil {
-				return err
-			}
+	l.log.Infow("start downloading the ebook", "book_id", book.ID, "title", book.Title)
+	l.log.Debugw("available ebook", "book_id", book.ID, "formats", book.Files.Books)
+	defer l.log.Infow("finish downloading the ebook", "book_id", book.ID, "title", book.Title)
+	for _, key := range l.selectFormats(CategoryEbook, book.Files.Books, book) {
+		if err := l.downloadByAddresses(ctx, basepath, "e-book", key, book.Files.Books[key], book); err != nil {
+			return err
 		}
 	}
 
@@ -180,25 +221,42 @@ func (l *Loader) downloadBook(ctx context.Context, basepath string, book book.Bo
 }
 
 func (l *Loader) downloadCover(ctx context.Context, basepath string, book book.Book) error {
-	l.log.Infof("start downloading are cover for the book %q, ", book.Title)
-	defer l.log.Infof("finishing downloading are cover for the book %q, ", book.Title)
+	l.log.Infow("start downloading the cover", "book_id", book.ID, "title", book.Title)
+	defer l.log.Infow("finish downloading the cover", "book_id", book.ID, "title", book.Title)
 	if err := l.downloadFileByURL(ctx, book.Cover.Large, basepath); err != nil {
 		return err
 	}
 
-	return l.downloadFileByURL(ctx, book.Cover.Small, basepath)
+	if err := l.downloadFileByURL(ctx, book.Cover.Small, basepath); err != nil {
+		return err
+	}
+
+	if book.NewCover == "" {
+		return nil
+	}
+
+	return l.downloadFile(ctx, book.NewCover, path.Join(basepath, newCoverName+urlExt(book.NewCover)))
+}
+
+// newCoverName it's the base name of the file of the new cover of the book.
+const newCoverName = "new-cover"
+
+// urlExt returns the extension of the file in the path of the URL.
+func urlExt(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return path.Ext(u.Path)
 }
 
 func (l *Loader) downloadDemo(ctx context.Context, basepath string, book book.Book) error {
-	l.log.Infof("start downloading are demo for the book %q", book.Title)
-	l.log.Debugf("available demo %s", book.Files.Demo)
-	defer l.log.Infof("finishing downloading are demo for the book %q, ", book.Title)
-	basepath = path.Join(basepath, "demo")
-	for key, as := range book.Files.Demo {
-		for _, address := range as {
-			if err := l.downloadByAddress(ctx, basepath, key, address, book); err != nil {
-				return err
-			}
+	l.log.Infow("start downloading the demo", "book_id", book.ID, "title", book.Title)
+	l.log.Debugw("available demo", "book_id", book.ID, "formats", book.Files.Demo)
+	defer l.log.Infow("finish downloading the demo", "book_id", book.ID, "title", book.Title)
+	for _, key := range l.selectFormats(CategoryDemo, book.Files.Demo, book) {
+		if err := l.downloadByAddresses(ctx, basepath, "demo", key, book.Files.Demo[key], book); err != nil {
+			return err
 		}
 	}
 
@@ -206,8 +264,8 @@ func (l *Loader) downloadDemo(ctx context.Context, basepath string, book book.Bo
 }
 
 func (l *Loader) downloadPhotos(ctx context.Context, basepath string, book book.Book) error {
-	l.log.Infof("start downloading are photos for the book %q, ", book.Title)
-	defer l.log.Infof("finishing downloading are photos for the book %q, ", book.Title)
+	l.log.Infow("start downloading the photos", "book_id", book.ID, "title", book.Title)
+	defer l.log.Infow("finish downloading the photos", "book_id", book.ID, "title", book.Title)
 	basepath = path.Join(basepath, "photos")
 	for _, as := range book.Photos {
 		if err := l.downloadFileByURL(ctx, as.URL, basepath); err != nil {
@@ -218,30 +276,95 @@ func (l *Loader) downloadPhotos(ctx context.Context, basepath string, book book.
 	return nil
 }
 
-func (l *Loader) downloadByAddress(ctx context.Context, basepath, ext string, ad book.Address, book book.Book) error {
-	title := ad.Title
-	if title == "" {
-		title = book.Title
+// item it's a single file of the book materials.
+type item struct {
+	category string
+	format   string
+	// index is the position of the address among the addresses of the
+	// same format.
+	index   int
+	parts   int
+	address book.Address
+}
+
+// newItems returns the files of the addresses of the same format.
+func newItems(category, format string, as book.Addresses) []item {
+	items := make([]item, 0, len(as))
+	for i, address := range as {
+		items = append(items, item{
+			category: category,
+			format:   format,
+			index:    i,
+			parts:    len(as),
+			address:  address,
+		})
+	}
+
+	return items
+}
+
+// addressItems returns the files of all the materials of the book which
+// are stored by addresses in the order of categories and formats.
+func addressItems(bk book.Book) []item {
+	var items []item
+	for _, category := range []struct {
+		name    string
+		formats book.Formats
+	}{
+		{name: "audiobook", formats: bk.Files.AudioBooks},
+		{name: "e-book", formats: bk.Files.Books},
+		{name: "demo", formats: bk.Files.Demo},
+	} {
+		keys := make([]string, 0, len(category.formats))
+		for key := range category.formats {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			items = append(items, newItems(category.name, key, category.formats[key])...)
+		}
+	}
+
+	return items
+}
+
+func (l *Loader) downloadByAddresses(ctx context.Context, basepath, category, ext string, as book.Addresses, book book.Book) error {
+	names, err := l.layout.fileNames(basepath, book)
+	if err != nil {
+		return err
+	}
+
+	for _, it := range newItems(category, ext, as) {
+		filename := filepath.Join(basepath, filepath.FromSlash(names[it.key()]))
+		if err := l.downloadByAddress(ctx, filename, it.address); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (l *Loader) downloadByAddress(ctx context.Context, filename string, ad book.Address) error {
+	if l.plan != nil {
+		filename = l.filePath(filename)
+		return l.planFile(filename, filename, ad.URL, ad.Size)
 	}
-	msg := fmt.Sprintf("%s.%s", title, ext)
 
-	filename := path.Join(basepath, ext, msg)
-	filename = cutter(filename)
 	if exist, err := osutil.FileExists(filename); exist && err == nil && ad.Size != 0 {
 		info, err := os.Stat(filename)
 		if err != nil {
 			return err
 		}
 		if int64(ad.Size) == info.Size() {
-			l.log.Debugf("skip downloading url %q because file %q exist with equal size: %d",
-				filename, ad.URL, ad.Size)
+			l.log.Debugw("skip downloading the file which exists with the equal size",
+				"url", ad.URL, "path", filename, "bytes", ad.Size)
 			return nil
 		}
 	} else if err != nil {
 		return err
 	}
 
-	return l.downloadFile(ctx, ad.URL, filename)
+	return l.fetch(ctx, ad.URL, filename, ad.Size)
 }
 
 func (l *Loader) downloadFileByURL(ctx context.Context, url, basepath string) error {
@@ -249,39 +372,76 @@ func (l *Loader) downloadFileByURL(ctx context.Context, url, basepath string) er
 }
 
 func (l *Loader) downloadFile(ctx context.Context, fileURL, filename string) error {
-	filename = clearBase(filename)
-	filename = cutter(filename)
+	return l.fetch(ctx, fileURL, filename, 0)
+}
+
+// fetch downloads the file of the size, zero is the unknown size. The
+// quota is charged only by the bytes written by the download, the skipped
+// and the failed files are not counted.
+func (l *Loader) fetch(ctx context.Context, fileURL, filename string, size uint) error {
+	filename = l.filePath(filename)
+	if l.plan != nil {
+		return l.planFile(filename, filename, fileURL, size)
+	}
+	if err := l.waitSpace(ctx, uint64(size)); err != nil {
+		return err
+	}
+	if !l.quota.take(uint64(size)) {
+		l.log.Infow("skip downloading the file because the download quota is reached",
+			"url", fileURL, "path", filename, "bytes", size)
+		return errQuotaExceeded
+	}
 
+	before, _ := os.Stat(filename)
 	err := l.api.DownloadFile(ctx, fileURL, filename)
+	var written uint64
+	if err == nil {
+		written = writtenBytes(before, filename)
+	}
+	l.quota.settle(uint64(size), written)
+
 	if err, ok := err.(*url.Error); ok {
 		if err.Err.Error() == "stopped after 10 redirects" {
-			l.log.Warnf("skip redirect error: %q", err)
+			l.log.Warnw("skip the redirect error", "url", fileURL, "path", filename, "error", err)
 			return nil
 		}
 	}
 
 	if err, ok := err.(*api.Error); ok && err.Code == 404 {
-		l.log.Warnf("skip undiscovered files with error %q", err)
+		l.log.Warnw("skip the undiscovered file", "url", fileURL, "path", filename, "error", err)
 		return nil
 	}
 
 	return err
 }
 
-// cutter this function is designed to trim file names that are too long
-// due to file system restrictions.
-func cutter(filename string) string {
-	base := filepath.Base(filename)
-
-	if len(base) > 255 {
-		ext := filepath.Ext(filename)
-
-		name := strings.TrimSuffix(base, ext)
-		name = name[:len(name)-1]
-		strings.ToValidUTF8(name, "")
+// partFile returns the name of the temporary file into which the file is
+// downloaded before it's renamed to its name. The name is short and plain
+// to stay the same under every profile, it's hidden so it's neither linked
+// into the books nor recorded to the manifest.
+func partFile(filename string) string {
+	sum := crc32.ChecksumIEEE([]byte(filepath.Base(filename)))
+	return filepath.Join(filepath.Dir(filename), fmt.Sprintf(".part-%08x", sum))
+}
 
-		return cutter(filepath.Join(filepath.Dir(filename), name+ext))
+// writtenBytes returns the size of the file if it's written after the
+// state before, zero if the file is left unchanged or missing.
+func writtenBytes(before os.FileInfo, filename string) uint64 {
+	after, err := os.Stat(filename)
+	if err != nil || !after.Mode().IsRegular() {
+		return 0
 	}
+	if before != nil && os.SameFile(before, after) && before.Size() == after.Size() &&
+		before.ModTime().Equal(after.ModTime()) {
+		return 0
+	}
+
+	return uint64(after.Size())
+}
 
-	return filename
+// filePath returns the path of the file cleaned by the profile of the
+// file system.
+func (l *Loader) filePath(filename string) string {
+	filename = l.layout.profile.clearBase(filename)
+	return l.layout.profile.fitFile(filename)
 }
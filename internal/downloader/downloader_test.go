@@ -14,6 +14,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -202,6 +203,20 @@ func TestLoader_downloadCover(t *testing.T) {
 			},
 		}))
 	})
+
+	t.Run("new cover", func(t *testing.T) {
+		amk.On("DownloadFile", ctx, "https://big.png", "jedi/big.png").Return(nil).Once()
+		amk.On("DownloadFile", ctx, "https://s.png", "jedi/s.png").Return(nil).Once()
+		amk.On("DownloadFile", ctx, "https://new/cover.jpg?size=1", "jedi/new-cover.jpg").Return(nil).Once()
+		require.NoError(t, l.downloadCover(ctx, "jedi", book.Book{
+			Title: "Джедайские техники",
+			Cover: book.Cover{
+				Small: "https://s.png",
+				Large: "https://big.png",
+			},
+			NewCover: "https://new/cover.jpg?size=1",
+		}))
+	})
 }
 
 func TestLoader_downloadDemo(t *testing.T) {
@@ -446,22 +461,35 @@ func TestLoader_Worker(t *testing.T) {
 }
 
 func Test_cutter(t *testing.T) {
-	tests := map[string]string{}
-
 	const maxFileNameLen = 255
-	for i := maxFileNameLen; i < 1024; i++ {
-		tests[genStaticFileName(i)] = genStaticFileName(maxFileNameLen)
+
+	names := map[string]bool{}
+	for i := maxFileNameLen + 1; i < 1024; i++ {
+		arg := genStaticFileName(i)
+		got := cutter(arg)
+		require.Len(t, filepath.Base(got), maxFileNameLen)
+		require.Equal(t, filepath.Dir(arg), filepath.Dir(got))
+		require.Equal(t, ".txt", filepath.Ext(got))
+		require.True(t, strings.HasPrefix(filepath.Base(got),
+			strings.Repeat("w", maxFileNameLen-len(".txt")-hashSuffixLen)+"~"))
+		require.False(t, names[got], "truncated names should be unique")
+		require.Equal(t, got, cutter(arg), "truncation should be stable")
+		names[got] = true
 	}
 
 	for i := maxFileNameLen; i > 5; i-- {
-		tests[genStaticFileName(i)] = genStaticFileName(i)
+		arg := genStaticFileName(i)
+		require.Equal(t, arg, cutter(arg))
 	}
 
-	for arg, want := range tests {
+	t.Run("utf-8", func(t *testing.T) {
+		arg := "/books/" + strings.Repeat("ж", 200) + ".mp3"
 		got := cutter(arg)
-		require.Equalf(t, want, got, "want length: %d, got length: %d", len(want), len(got))
-		require.Equal(t, filepath.Dir(want), filepath.Dir(got), "want dir: %q, got dir: %q")
-	}
+		require.True(t, utf8.ValidString(got))
+		require.True(t, len(filepath.Base(got)) <= maxFileNameLen)
+		require.True(t, len(filepath.Base(got)) > maxFileNameLen-2)
+		require.Equal(t, ".mp3", filepath.Ext(got))
+	})
 
 	t.Run("test on the example of a specific case for book 3344", func(t *testing.T) {
 		golden.Equal(t, []byte(cutter(string(golden.Read(t)))))
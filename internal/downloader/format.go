@@ -0,0 +1,203 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package downloader
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/xorcare/miflib.go/internal/book"
+)
+
+// DefaultFormatRules it's the rules of the formats used by default. OGG and
+// MP3 recordings are compressed for web playback of books, the recordings
+// of higher quality stored in a zip archive are preferred to them.
+var DefaultFormatRules = map[Category]string{
+	CategoryEbook:     "*",
+	CategoryAudiobook: "*,zip>mp3+ogg",
+	CategoryDemo:      "*",
+}
+
+// FormatRule it's the rule which selects the formats of the materials to
+// download from the formats available for the book.
+//
+// The rule is a comma separated list of terms: "*" selects all available
+// formats, "epub" selects the format, "-fb2" excludes the format and
+// "m4b>zip" selects only the first available alternative, an alternative
+// may consist of several formats as in "zip>mp3+ogg". If the rule contains
+// only exclusions, all other formats are selected. For example "epub,pdf"
+// downloads only epub and pdf, "m4b>zip>mp3" only the best available
+// format and "*,-fb2" everything except fb2.
+type FormatRule struct {
+	source   string
+	all      bool
+	formats  []string
+	excluded []string
+	// chains is the lists of the alternatives ordered by preference, each
+	// alternative is a list of formats.
+	chains [][][]string
+}
+
+// ParseFormatRule parses the rule of the formats.
+func ParseFormatRule(s string) (FormatRule, error) {
+	rule := FormatRule{source: s}
+	positive := false
+	for _, term := range strings.Split(s, ",") {
+		term = strings.ToLower(strings.TrimSpace(term))
+		switch {
+		case term == "":
+		case term == "*":
+			rule.all, positive = true, true
+		case strings.HasPrefix(term, "-"):
+			format := strings.TrimSpace(term[1:])
+			if !validFormat(format) {
+				return FormatRule{}, fmt.Errorf("downloader: invalid format %q in the rule %q", term, s)
+			}
+			rule.excluded = append(rule.excluded, format)
+		case strings.ContainsAny(term, ">+"):
+			var chain [][]string
+			for _, alternative := range strings.Split(term, ">") {
+				var formats []string
+				for _, format := range strings.Split(alternative, "+") {
+					format = strings.TrimSpace(format)
+					if !validFormat(format) {
+						return FormatRule{}, fmt.Errorf("downloader: invalid format %q in the rule %q", format, s)
+					}
+					formats = append(formats, format)
+				}
+				chain = append(chain, formats)
+			}
+			rule.chains, positive = append(rule.chains, chain), true
+		default:
+			if !validFormat(term) {
+				return FormatRule{}, fmt.Errorf("downloader: invalid format %q in the rule %q", term, s)
+			}
+			rule.formats, positive = append(rule.formats, term), true
+		}
+	}
+
+	if !positive {
+		rule.all = true
+	}
+
+	return rule, nil
+}
+
+func validFormat(format string) bool {
+	return format != "" && !strings.ContainsAny(format, "*-+>/ ")
+}
+
+// String returns the source of the rule.
+func (r FormatRule) String() string {
+	return r.source
+}
+
+// Select returns the sorted formats which should be downloaded from the
+// available formats.
+func (r FormatRule) Select(available []string) []string {
+	if r.source == "" {
+		// the zero rule selects all formats.
+		r.all = true
+	}
+
+	has := make(map[string]bool, len(available))
+	for _, format := range available {
+		has[format] = true
+	}
+
+	selected := make(map[string]bool, len(available))
+	if r.all {
+		for _, format := range available {
+			selected[format] = true
+		}
+	}
+	for _, format := range r.formats {
+		selected[format] = true
+	}
+
+	for _, chain := range r.chains {
+		best := -1
+		for i, alternative := range chain {
+			for _, format := range alternative {
+				if best < 0 && has[format] {
+					best = i
+				}
+			}
+		}
+		for i, alternative := range chain {
+			for _, format := range alternative {
+				selected[format] = i == best
+			}
+		}
+	}
+
+	for _, format := range r.excluded {
+		selected[format] = false
+	}
+
+	formats := make([]string, 0, len(selected))
+	for _, format := range available {
+		if selected[format] {
+			formats = append(formats, format)
+		}
+	}
+	sort.Strings(formats)
+
+	return formats
+}
+
+// formatRule returns the rule of the formats of the category.
+func (l *Loader) formatRule(category Category) FormatRule {
+	if rule, ok := l.formats[category]; ok {
+		return rule
+	}
+
+	rule, err := ParseFormatRule(DefaultFormatRules[category])
+	if err != nil {
+		panic(err)
+	}
+
+	return rule
+}
+
+// selectFormats returns the formats of the category which should be
+// downloaded, the skipped formats are logged.
+func (l *Loader) selectFormats(category Category, formats book.Formats, bk book.Book) []string {
+	available := make([]string, 0, len(formats))
+	for format := range formats {
+		available = append(available, format)
+	}
+	sort.Strings(available)
+
+	rule := l.formatRule(category)
+	selected := rule.Select(available)
+	for _, format := range available {
+		if !containsString(selected, format) {
+			l.log.Infow("skip the format excluded by the rule",
+				"book_id", bk.ID, "category", category, "format", format, "rule", rule.String())
+		}
+	}
+
+	allowed := selected[:0]
+	for _, format := range selected {
+		if ignored, by := l.ignore.Material(bk, string(category), format); ignored {
+			l.log.Infow("skip the ignored format", "book_id", bk.ID, "category", category, "format", format, "rule", by)
+			continue
+		}
+		allowed = append(allowed, format)
+	}
+
+	return allowed
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package downloader
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/xorcare/miflib.go/internal/book"
+	"github.com/xorcare/miflib.go/internal/ctxtest"
+)
+
+func TestFormatRule_Select(t *testing.T) {
+	tests := []struct {
+		rule      string
+		available []string
+		want      []string
+	}{
+		{rule: "", available: []string{"pdf", "epub"}, want: []string{"epub", "pdf"}},
+		{rule: "*", available: []string{"fb2", "epub"}, want: []string{"epub", "fb2"}},
+		{rule: "epub,pdf", available: []string{"fb2", "epub", "mobi"}, want: []string{"epub"}},
+		{rule: "-fb2", available: []string{"fb2", "epub", "mobi"}, want: []string{"epub", "mobi"}},
+		{rule: "*, -FB2", available: []string{"fb2", "epub"}, want: []string{"epub"}},
+		{rule: "*,zip>mp3+ogg", available: []string{"mp3", "ogg", "zip"}, want: []string{"zip"}},
+		{rule: "*,zip>mp3+ogg", available: []string{"mp3", "ogg"}, want: []string{"mp3", "ogg"}},
+		{rule: "*,zip>mp3+ogg", available: []string{"m4b", "ogg", "zip"}, want: []string{"m4b", "zip"}},
+		{rule: "m4b>zip>mp3", available: []string{"mp3", "ogg", "zip"}, want: []string{"zip"}},
+		{rule: "m4b>zip>mp3", available: []string{"m4b", "mp3", "zip"}, want: []string{"m4b"}},
+		{rule: "m4b>zip>mp3", available: []string{"ogg"}, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule+" "+strings.Join(tt.available, ","), func(t *testing.T) {
+			rule, err := ParseFormatRule(tt.rule)
+			require.NoError(t, err)
+			require.Equal(t, tt.rule, rule.String())
+			require.Equal(t, tt.want, rule.Select(tt.available))
+		})
+	}
+
+	t.Run("zero", func(t *testing.T) {
+		require.Equal(t, []string{"epub"}, FormatRule{}.Select([]string{"epub"}))
+	})
+}
+
+func TestParseFormatRule(t *testing.T) {
+	for _, rule := range []string{"-", "zip>", "mp3++ogg", "e pub", "-*"} {
+		_, err := ParseFormatRule(rule)
+		require.Error(t, err, rule)
+	}
+}
+
+func TestOptFormatRule(t *testing.T) {
+	amk := new(apiMock)
+	amk.Test(t)
+	defer amk.AssertExpectations(t)
+
+	ebook, err := ParseFormatRule("epub,pdf")
+	require.NoError(t, err)
+	audiobook, err := ParseFormatRule("m4b>zip>mp3")
+	require.NoError(t, err)
+
+	l := NewLoader("", amk, zap.NewNop().Sugar(),
+		OptCategories(CategoryEbook, CategoryAudiobook),
+		OptFormatRule(CategoryEbook, ebook),
+		OptFormatRule(CategoryAudiobook, audiobook),
+	)
+
+	amk.On("DownloadFile", ctxtest.Match, "https://epub", "jedi/e-book/epub/Джедайские техники.epub").Return(nil).Once()
+	amk.On("DownloadFile", ctxtest.Match, "https://zip", "jedi/audiobook/zip/Джедайские техники.zip").Return(nil).Once()
+
+	require.NoError(t, l.download(ctxtest.Background(), "jedi", book.Book{
+		Title: "Джедайские техники",
+		Files: book.Files{
+			Books: book.Formats{
+				"epub": {{URL: "https://epub"}},
+				"fb2":  {{URL: "https://fb2"}},
+			},
+			AudioBooks: book.Formats{
+				"zip": {{URL: "https://zip"}},
+				"mp3": {{URL: "https://mp3"}},
+			},
+		},
+	}))
+}
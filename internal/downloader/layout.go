@@ -0,0 +1,272 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package downloader
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/xorcare/miflib.go/internal/book"
+)
+
+// Templates of the layout used by default.
+const (
+	// DefaultDirTemplate it's the template of the book directory.
+	DefaultDirTemplate = `{{printf "%05d" .ID}} {{.Title}}`
+	// DefaultFileTemplate it's the template of the files of the ebooks,
+	// audiobooks and demo materials.
+	DefaultFileTemplate = `{{.Category}}/{{.Format}}/{{.Title}}.{{.Format}}`
+)
+
+var defaultLayout = mustLayout(NewLayout(DefaultDirTemplate, DefaultFileTemplate))
+
+// Layout describes how the books and their files are placed in the library.
+type Layout struct {
+	dir     *template.Template
+	file    *template.Template
+	profile Profile
+}
+
+// NewLayout creates new layout from the templates of the book directory
+// and of the book files. The directory template is executed with the
+// bookData, the file template with the fileData. The output of templates
+// may contain slashes to place files in nested directories, every element
+// of the path is cleared from the characters forbidden by the profile of
+// the layout, empty elements are skipped.
+func NewLayout(dir, file string) (Layout, error) {
+	dirTmpl, err := template.New("dir").Funcs(funcs).Option("missingkey=error").Parse(dir)
+	if err != nil {
+		return Layout{}, fmt.Errorf("downloader: invalid directory template: %v", err)
+	}
+
+	fileTmpl, err := template.New("file").Funcs(funcs).Option("missingkey=error").Parse(file)
+	if err != nil {
+		return Layout{}, fmt.Errorf("downloader: invalid file template: %v", err)
+	}
+
+	return Layout{dir: dirTmpl, file: fileTmpl}, nil
+}
+
+// WithProfile returns the copy of the layout which clears the names by
+// the rules of the file system profile.
+func (l Layout) WithProfile(profile Profile) Layout {
+	l.profile = profile
+	return l
+}
+
+func mustLayout(l Layout, err error) Layout {
+	if err != nil {
+		panic(err)
+	}
+	return l
+}
+
+// BookDir returns the path to the directory of the book relative to the
+// library root.
+func (l Layout) BookDir(bk book.Book) (string, error) {
+	tmpl := l.dir
+	if tmpl == nil {
+		tmpl = defaultLayout.dir
+	}
+
+	buf := bytes.Buffer{}
+	if err := tmpl.Execute(&buf, newBookData(bk, l.profile)); err != nil {
+		return "", fmt.Errorf("downloader: directory template of the book %d: %v", bk.ID, err)
+	}
+
+	dir := clearPath(buf.String(), l.profile)
+	if dir == "" {
+		return "", fmt.Errorf("downloader: directory template of the book %d yields an empty path", bk.ID)
+	}
+
+	return l.profile.rules().limits.fit("", dir, 0), nil
+}
+
+// bookPath returns the path to the directory of the book in the library
+// root, a third of the path limit is reserved for the files of the book.
+func (l Layout) bookPath(root string, bk book.Book) (string, error) {
+	dir, err := l.BookDir(bk)
+	if err != nil {
+		return "", err
+	}
+
+	lim := l.profile.rules().limits
+	dir = lim.fit(root, dir, lim.path-lim.path/3)
+
+	return filepath.Join(root, filepath.FromSlash(dir)), nil
+}
+
+// fileName returns the slash separated path to the file of the book
+// relative to the book directory, the path is trimmed to the limits of
+// the file system.
+func (l Layout) fileName(bookpath string, bk book.Book, it item) (string, error) {
+	tmpl := l.file
+	if tmpl == nil {
+		tmpl = defaultLayout.file
+	}
+
+	buf := bytes.Buffer{}
+	if err := tmpl.Execute(&buf, newFileData(bk, it, l.profile)); err != nil {
+		return "", fmt.Errorf("downloader: file template of the book %d: %v", bk.ID, err)
+	}
+
+	name := clearPath(buf.String(), l.profile)
+	if name == "" {
+		return "", fmt.Errorf("downloader: file template of the book %d yields an empty path", bk.ID)
+	}
+
+	lim := l.profile.rules().limits
+
+	return lim.fit(bookpath, name, lim.path), nil
+}
+
+// Validate checks that the layout places every book into a separate
+// directory which is not nested in the directory of another book.
+// Directories that differ only in case are considered the same because
+// of case-insensitive file systems.
+func (l Layout) Validate(bks []book.Book) error {
+	type entry struct {
+		dir string
+		id  int
+	}
+
+	entries := make([]entry, 0, len(bks))
+	for _, bk := range bks {
+		dir, err := l.BookDir(bk)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry{dir: strings.ToLower(dir) + "/", id: bk.ID})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].dir < entries[j].dir
+	})
+
+	for i := 1; i < len(entries); i++ {
+		prev, cur := entries[i-1], entries[i]
+		switch {
+		case cur.dir == prev.dir:
+			return fmt.Errorf("downloader: directory template places the books %d and %d into"+
+				" the same directory %q", prev.id, cur.id, strings.TrimSuffix(cur.dir, "/"))
+		case strings.HasPrefix(cur.dir, prev.dir):
+			return fmt.Errorf("downloader: directory template places the book %d inside"+
+				" the directory %q of the book %d", cur.id, strings.TrimSuffix(prev.dir, "/"), prev.id)
+		}
+	}
+
+	return nil
+}
+
+// bookData it's the data available in the templates of the layout, all
+// values are already cleared from the characters forbidden by the profile.
+type bookData struct {
+	ID       int
+	Title    string
+	Subtitle string
+	// Author is the name of the first author of the book.
+	Author  string
+	Authors []string
+	// Badge is the first badge of the book.
+	Badge  string
+	Badges []string
+}
+
+func newBookData(bk book.Book, profile Profile) bookData {
+	data := bookData{
+		ID:       bk.ID,
+		Title:    profile.clean(bk.Title.String()),
+		Subtitle: profile.clean(bk.Subtitle),
+	}
+
+	for _, author := range bk.Authors {
+		data.Authors = append(data.Authors, profile.clean(author.Name))
+	}
+	if len(data.Authors) > 0 {
+		data.Author = data.Authors[0]
+	}
+
+	for _, badge := range bk.Badges {
+		data.Badges = append(data.Badges, profile.clean(badge))
+	}
+	if len(data.Badges) > 0 {
+		data.Badge = data.Badges[0]
+	}
+
+	return data
+}
+
+// fileData it's the data available in the file template of the layout,
+// all values are already cleared from the characters forbidden by the
+// profile.
+type fileData struct {
+	// Category is the kind of the materials: audiobook, e-book or demo.
+	Category string
+	// Format is the format of the file, for example epub or mp3.
+	Format string
+	// Index is the zero-based position of the file among the files of
+	// the same format.
+	Index int
+	// Part is the one-based position of the file among the files of
+	// the same format.
+	Part int
+	// Parts is the number of the files of the same format.
+	Parts int
+	// Title is the title of the file or the book title if the file has
+	// no title.
+	Title string
+	// Author is the name of the first author of the book.
+	Author string
+	// Book is the data of the book.
+	Book bookData
+}
+
+func newFileData(bk book.Book, it item, profile Profile) fileData {
+	data := fileData{
+		Category: profile.clean(it.category),
+		Format:   profile.clean(it.format),
+		Index:    it.index,
+		Part:     it.index + 1,
+		Parts:    it.parts,
+		Title:    profile.clean(it.address.Title.String()),
+		Book:     newBookData(bk, profile),
+	}
+
+	if data.Title == "" {
+		data.Title = data.Book.Title
+	}
+	data.Author = data.Book.Author
+
+	return data
+}
+
+// funcs it's the functions available in the templates of the layout.
+var funcs = template.FuncMap{
+	// join concatenates the elements, for example {{join .Authors ", "}}.
+	"join": func(elems []string, sep string) string {
+		return strings.Join(elems, sep)
+	},
+	// pad pads the number with zeros, for example {{pad 5 .ID}}.
+	"pad": func(width, n int) string {
+		return fmt.Sprintf("%0*d", width, n)
+	},
+}
+
+// clearPath clears every element of the slash separated path.
+func clearPath(s string, profile Profile) string {
+	var elems []string
+	for _, elem := range strings.Split(s, "/") {
+		if elem = profile.clean(elem); elem != "" {
+			elems = append(elems, elem)
+		}
+	}
+
+	return path.Join(elems...)
+}
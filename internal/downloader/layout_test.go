@@ -0,0 +1,132 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package downloader
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/xorcare/miflib.go/internal/book"
+)
+
+func TestLayout_BookDir(t *testing.T) {
+	bk := book.Book{
+		ID:      42,
+		Title:   "Джедайские техники: как воспитать свою обезьяну?",
+		Authors: []book.Author{{Name: "Максим Дорофеев"}, {Name: "Ирина Гусинская"}},
+		Badges:  []string{"new"},
+	}
+
+	tests := map[string]string{
+		"":                                       "00042 Джедайские техники как воспитать свою обезьяну",
+		DefaultDirTemplate:                       "00042 Джедайские техники как воспитать свою обезьяну",
+		"{{.Author}}/{{.Title}} ({{.ID}})":       "Максим Дорофеев/Джедайские техники как воспитать свою обезьяну (42)",
+		"{{.Badge}}/{{pad 3 .ID}}":               "new/042",
+		`{{join .Authors ", "}}/{{.ID}}`:         "Максим Дорофеев, Ирина Гусинская/42",
+		"{{.Subtitle}}/../{{.ID}}":               "42",
+		`{{or .Subtitle "no subtitle"}}/{{.ID}}`: "no subtitle/42",
+	}
+
+	for tmpl, want := range tests {
+		t.Run(tmpl, func(t *testing.T) {
+			layout := Layout{}
+			if tmpl != "" {
+				var err error
+				layout, err = NewLayout(tmpl, DefaultFileTemplate)
+				require.NoError(t, err)
+			}
+
+			got, err := layout.BookDir(bk)
+			require.NoError(t, err)
+			require.Equal(t, want, got)
+		})
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		layout, err := NewLayout("{{.Subtitle}}", DefaultFileTemplate)
+		require.NoError(t, err)
+		_, err = layout.BookDir(bk)
+		require.Error(t, err)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := NewLayout("{{.Title", DefaultFileTemplate)
+		require.Error(t, err)
+	})
+}
+
+func TestLayout_Validate(t *testing.T) {
+	bks := []book.Book{
+		{ID: 1, Title: "Книга", Authors: []book.Author{{Name: "Автор"}}},
+		{ID: 2, Title: "книга", Authors: []book.Author{{Name: "Автор"}}},
+		{ID: 3, Title: "Другая книга"},
+	}
+
+	require.NoError(t, defaultLayout.Validate(bks))
+
+	layout, err := NewLayout("{{.Author}}/{{.Title}}", DefaultFileTemplate)
+	require.NoError(t, err)
+	require.EqualError(t, layout.Validate(bks), "downloader: directory template places the books"+
+		" 1 and 2 into the same directory \"автор/книга\"")
+
+	layout, err = NewLayout("{{.Author}}/{{.ID}}", DefaultFileTemplate)
+	require.NoError(t, err)
+	require.NoError(t, layout.Validate(bks))
+
+	layout, err = NewLayout("{{.Author}}/{{.Title}}", DefaultFileTemplate)
+	require.NoError(t, err)
+	nested := []book.Book{bks[0], {ID: 4, Title: "Автор"}}
+	require.EqualError(t, layout.Validate(nested), "downloader: directory template places the book"+
+		" 1 inside the directory \"автор\" of the book 4")
+}
+
+func TestLayout_fileName(t *testing.T) {
+	bk := book.Book{
+		ID:      42,
+		Title:   "Джедайские техники",
+		Authors: []book.Author{{Name: "Максим Дорофеев"}},
+	}
+	it := item{
+		category: "audiobook",
+		format:   "mp3",
+		index:    2,
+		parts:    12,
+		address:  book.Address{Title: "Глава 2. Обезьяна?"},
+	}
+
+	tests := map[string]string{
+		"":                  "audiobook/mp3/Глава 2. Обезьяна.mp3",
+		DefaultFileTemplate: "audiobook/mp3/Глава 2. Обезьяна.mp3",
+		"{{.Category}}/{{pad 2 .Part}} {{.Title}}.{{.Format}}":             "audiobook/03 Глава 2. Обезьяна.mp3",
+		"{{.Author}}/{{.Book.Title}}/{{.Index}} of {{.Parts}}.{{.Format}}": "Максим Дорофеев/Джедайские техники/2 of 12.mp3",
+	}
+
+	for tmpl, want := range tests {
+		t.Run(tmpl, func(t *testing.T) {
+			layout := Layout{}
+			if tmpl != "" {
+				var err error
+				layout, err = NewLayout(DefaultDirTemplate, tmpl)
+				require.NoError(t, err)
+			}
+
+			got, err := layout.fileName("jedi", bk, it)
+			require.NoError(t, err)
+			require.Equal(t, want, got)
+		})
+	}
+
+	t.Run("untitled", func(t *testing.T) {
+		got, err := defaultLayout.fileName("jedi", bk, item{category: "e-book", format: "epub"})
+		require.NoError(t, err)
+		require.Equal(t, "e-book/epub/Джедайские техники.epub", got)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := NewLayout(DefaultDirTemplate, "{{.Title")
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,282 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/xorcare/miflib.go/internal/book"
+	"github.com/xorcare/miflib.go/internal/library"
+)
+
+// Problem it's the damage of the file found by the verification.
+type Problem string
+
+// Problems of the files.
+const (
+	// ProblemMissing it's the file of the manifest which is missing on the
+	// disk.
+	ProblemMissing Problem = "missing"
+	// ProblemCorrupt it's the file which checksum or size differs from the
+	// manifest.
+	ProblemCorrupt Problem = "corrupt"
+	// ProblemSize it's the file which size differs from the catalog.
+	ProblemSize Problem = "size mismatch"
+)
+
+// Damage it's the damaged file of the book.
+type Damage struct {
+	// Path is the path of the file relative to the directory of the book.
+	Path    string
+	Problem Problem
+	// Repaired reports whether the file is downloaded again and matches
+	// the catalog.
+	Repaired bool
+}
+
+// Verification it's the result of the verification of the book.
+type Verification struct {
+	ID    int
+	Title string
+	Path  string
+	// Files is the number of the files in the manifest.
+	Files int
+	// NoManifest reports that the book has no manifest, such books are
+	// downloaded before the manifests were introduced and are not synced
+	// since then.
+	NoManifest bool
+	Damages    []Damage
+}
+
+// writeManifest records the checksums of the files of the book to its
+// manifest, the service files and the hidden files are not recorded.
+func (l *Loader) writeManifest(bookpath string, bk book.Book) error {
+	addresses, err := l.catalogFiles(bookpath, bk)
+	if err != nil {
+		return err
+	}
+
+	var m library.Manifest
+	err = filepath.Walk(bookpath, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if name != bookpath && strings.HasPrefix(info.Name(), ".") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() || name == filepath.Join(bookpath, library.BookFile) ||
+			name == filepath.Join(bookpath, library.ManifestFile) {
+			return nil
+		}
+
+		sum, size, err := library.Checksum(name)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(bookpath, name)
+		if err != nil {
+			return err
+		}
+
+		ad := addresses[name]
+		m.Files = append(m.Files, library.ManifestEntry{
+			Path:        filepath.ToSlash(rel),
+			Size:        size,
+			SHA256:      sum,
+			URL:         ad.URL,
+			CatalogSize: ad.Size,
+		})
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return library.WriteManifest(bookpath, m)
+}
+
+// backfillManifest writes the manifest of the downloaded book which has
+// none. The checksums of such manifest record the current contents of the
+// files, so the damage which happened before can't be found by them, only
+// the sizes of the files are still checked against the catalog.
+func (l *Loader) backfillManifest(bookpath string, bk book.Book) error {
+	_, err := library.ReadManifest(bookpath)
+	if !os.IsNotExist(err) {
+		return err
+	}
+
+	l.log.Infow("record the manifest of the book downloaded earlier", "book_id", bk.ID, "path", bookpath)
+
+	return l.writeManifest(bookpath, bk)
+}
+
+// renameManifest updates the paths of the files moved inside the book
+// directory in its manifest, the book without the manifest is skipped.
+func renameManifest(bookpath string, renamed map[string]string) error {
+	if len(renamed) == 0 {
+		return nil
+	}
+
+	m, err := library.ReadManifest(bookpath)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for i, file := range m.Files {
+		if to, ok := renamed[file.Path]; ok {
+			m.Files[i].Path = to
+		}
+	}
+
+	return library.WriteManifest(bookpath, m)
+}
+
+// catalogFiles returns the addresses of the materials of the book by the
+// names of their files.
+func (l *Loader) catalogFiles(bookpath string, bk book.Book) (map[string]book.Address, error) {
+	names, err := l.layout.fileNames(bookpath, bk)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := make(map[string]book.Address)
+	for _, it := range addressItems(bk) {
+		filename := filepath.Join(bookpath, filepath.FromSlash(names[it.key()]))
+		addresses[l.filePath(filename)] = it.address
+	}
+
+	byURL := func(dir, fileURL string) {
+		if fileURL != "" {
+			addresses[l.filePath(path.Join(dir, path.Base(fileURL)))] = book.Address{URL: fileURL}
+		}
+	}
+	byURL(bookpath, bk.Cover.Large)
+	byURL(bookpath, bk.Cover.Small)
+	for _, photo := range bk.Photos {
+		byURL(path.Join(bookpath, "photos"), photo.URL)
+	}
+	if bk.NewCover != "" {
+		filename := path.Join(bookpath, newCoverName+urlExt(bk.NewCover))
+		addresses[l.filePath(filename)] = book.Address{URL: bk.NewCover}
+	}
+
+	return addresses, nil
+}
+
+// Verify checks the files of the book against its manifest and the sizes
+// from the catalog. If the repair is set the damaged files which have the
+// URL are downloaded again and the manifest is updated.
+func (l *Loader) Verify(ctx context.Context, entry library.Entry, repair bool) (Verification, error) {
+	v := Verification{ID: entry.Book.ID, Title: entry.Book.Title.String(), Path: entry.Path}
+
+	m, err := library.ReadManifest(entry.Path)
+	if os.IsNotExist(err) {
+		v.NoManifest = true
+		return v, nil
+	} else if err != nil {
+		return v, err
+	}
+	v.Files = len(m.Files)
+
+	repaired := false
+	for i, file := range m.Files {
+		filename := filepath.Join(entry.Path, filepath.FromSlash(file.Path))
+		problem, err := checkFile(filename, file)
+		if err != nil {
+			return v, err
+		}
+		if problem == "" {
+			continue
+		}
+
+		damage := Damage{Path: file.Path, Problem: problem}
+		if repair && file.URL != "" {
+			l.log.Infow("download the damaged file again", "book_id", v.ID, "url", file.URL,
+				"path", filename, "problem", problem)
+			sum, size, ok, err := l.repairFile(ctx, filename, file)
+			if err != nil {
+				return v, err
+			}
+			if ok {
+				m.Files[i].SHA256, m.Files[i].Size = sum, size
+				damage.Repaired, repaired = true, true
+			}
+		}
+		v.Damages = append(v.Damages, damage)
+	}
+
+	if repaired {
+		return v, library.WriteManifest(entry.Path, m)
+	}
+
+	return v, nil
+}
+
+// repairFile downloads the file again into the temporary file and replaces
+// the damaged one by it only if its size matches the catalog, so the file
+// which can't be downloaded or is downloaded damaged again is kept.
+func (l *Loader) repairFile(ctx context.Context, filename string, file library.ManifestEntry) (
+	sum string, size int64, ok bool, err error) {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return "", 0, false, err
+	}
+	// the name is short and plain to stay the same under every profile, the
+	// hidden files are not recorded to the manifest.
+	tmp := filepath.Join(filepath.Dir(filename), fmt.Sprintf(".repair-%08x", crc32.ChecksumIEEE([]byte(file.Path))))
+	defer os.Remove(tmp)
+
+	if err := l.fetch(ctx, file.URL, tmp, file.CatalogSize); err != nil {
+		return "", 0, false, err
+	}
+
+	sum, size, err = library.Checksum(tmp)
+	switch {
+	case os.IsNotExist(err):
+		l.log.Warnw("keep the damaged file which can't be downloaded", "url", file.URL, "path", filename)
+		return "", 0, false, nil
+	case err != nil:
+		return "", 0, false, err
+	case file.CatalogSize != 0 && int64(file.CatalogSize) != size:
+		l.log.Warnw("keep the damaged file since the downloaded one differs from the catalog",
+			"url", file.URL, "path", filename, "bytes", size, "catalog_bytes", file.CatalogSize)
+		return "", 0, false, nil
+	}
+
+	if err := os.Rename(tmp, filename); err != nil {
+		return "", 0, false, err
+	}
+
+	return sum, size, true, nil
+}
+
+// checkFile returns the problem of the file, the empty problem means the
+// file is intact.
+func checkFile(filename string, file library.ManifestEntry) (Problem, error) {
+	sum, size, err := library.Checksum(filename)
+	switch {
+	case os.IsNotExist(err):
+		return ProblemMissing, nil
+	case err != nil:
+		return "", err
+	case size != file.Size || sum != file.SHA256:
+		return ProblemCorrupt, nil
+	case file.CatalogSize != 0 && size != int64(file.CatalogSize):
+		return ProblemSize, nil
+	}
+
+	return "", nil
+}
@@ -0,0 +1,185 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package downloader
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/xorcare/miflib.go/internal/api"
+	"github.com/xorcare/miflib.go/internal/book"
+	"github.com/xorcare/miflib.go/internal/ctxtest"
+	"github.com/xorcare/miflib.go/internal/library"
+)
+
+func TestLoader_Verify(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	amk := new(apiMock)
+	amk.Test(t)
+	defer amk.AssertExpectations(t)
+	write := func(size int) func(args mock.Arguments) {
+		return func(args mock.Arguments) {
+			writeFile(t, args.String(2), size)
+		}
+	}
+
+	l := NewLoader(tempDir, amk, zap.NewNop().Sugar(), OptCategories(CategoryEbook, CategoryCover))
+
+	jedi := filepath.Join(tempDir, "00001 Джедайские техники")
+	epub := filepath.Join(jedi, "e-book/epub/Джедайские техники.epub")
+	pdf := filepath.Join(jedi, "e-book/pdf/Джедайские техники.pdf")
+	small := filepath.Join(jedi, "small.png")
+	amk.On("DownloadFile", ctxtest.Match, "https://epub", epub).Return(nil).Run(write(20)).Once()
+	amk.On("DownloadFile", ctxtest.Match, "https://pdf", pdf).Return(nil).Run(write(10)).Once()
+	amk.On("DownloadFile", ctxtest.Match, "https://cover/small.png", small).Return(nil).Run(write(3)).Once()
+	amk.On("DownloadFile", ctxtest.Match, "https://cover/large.png", filepath.Join(jedi, "large.png")).
+		Return(nil).Once()
+
+	// the files are repaired through the temporary files.
+	temporary := func(filename string) interface{} {
+		return mock.MatchedBy(func(name string) bool {
+			return filepath.Dir(name) == filepath.Dir(filename) && strings.HasPrefix(filepath.Base(name), ".repair-")
+		})
+	}
+	amk.On("DownloadFile", ctxtest.Match, "https://epub", temporary(epub)).Return(nil).Run(write(20)).Once()
+	amk.On("DownloadFile", ctxtest.Match, "https://pdf", temporary(pdf)).Return(nil).Run(write(10)).Twice()
+	amk.On("DownloadFile", ctxtest.Match, "https://cover/small.png", temporary(small)).
+		Return(nil).Run(write(3)).Once()
+	amk.On("DownloadFile", ctxtest.Match, "https://cover/small.png", temporary(small)).
+		Return(&api.Error{Code: 404}).Once()
+
+	ch := make(chan book.Book, 1)
+	ch <- book.Book{
+		ID:    1,
+		Title: "Джедайские техники",
+		Cover: book.Cover{Small: "https://cover/small.png", Large: "https://cover/large.png"},
+		Files: book.Files{Books: map[string]book.Addresses{
+			"epub": {{URL: "https://epub", Size: 20}},
+			"pdf":  {{URL: "https://pdf", Size: 12}},
+		}},
+	}
+	close(ch)
+	require.NoError(t, l.Worker(ctxtest.Background(), ch))
+
+	m, err := library.ReadManifest(jedi)
+	require.NoError(t, err)
+	require.Len(t, m.Files, 3)
+	require.Equal(t, library.ManifestEntry{
+		Path:        "e-book/epub/Джедайские техники.epub",
+		Size:        20,
+		SHA256:      "de47c9b27eb8d300dbb5f2c353e632c393262cf06340c4fa7f1b40c4cbd36f90",
+		URL:         "https://epub",
+		CatalogSize: 20,
+	}, m.Files[0])
+	require.Equal(t, "small.png", m.Files[2].Path)
+	require.Equal(t, "https://cover/small.png", m.Files[2].URL)
+	require.Zero(t, m.Files[2].CatalogSize)
+
+	entry, err := library.Read(jedi)
+	require.NoError(t, err)
+
+	v, err := l.Verify(ctxtest.Background(), entry, false)
+	require.NoError(t, err)
+	require.Equal(t, 3, v.Files)
+	require.Equal(t, []Damage{{Path: "e-book/pdf/Джедайские техники.pdf", Problem: ProblemSize}}, v.Damages)
+
+	require.NoError(t, ioutil.WriteFile(epub, []byte("rotten"), 0644))
+	require.NoError(t, os.Remove(small))
+
+	v, err = l.Verify(ctxtest.Background(), entry, true)
+	require.NoError(t, err)
+	require.Equal(t, []Damage{
+		{Path: "e-book/epub/Джедайские техники.epub", Problem: ProblemCorrupt, Repaired: true},
+		{Path: "e-book/pdf/Джедайские техники.pdf", Problem: ProblemSize},
+		{Path: "small.png", Problem: ProblemMissing, Repaired: true},
+	}, v.Damages)
+
+	v, err = l.Verify(ctxtest.Background(), entry, false)
+	require.NoError(t, err)
+	require.Equal(t, []Damage{{Path: "e-book/pdf/Джедайские техники.pdf", Problem: ProblemSize}}, v.Damages)
+	require.FileExists(t, pdf)
+
+	// the file which can't be downloaded again is kept.
+	require.NoError(t, ioutil.WriteFile(small, []byte("rot"), 0644))
+	v, err = l.Verify(ctxtest.Background(), entry, true)
+	require.NoError(t, err)
+	require.Equal(t, []Damage{
+		{Path: "e-book/pdf/Джедайские техники.pdf", Problem: ProblemSize},
+		{Path: "small.png", Problem: ProblemCorrupt},
+	}, v.Damages)
+	data, err := ioutil.ReadFile(small)
+	require.NoError(t, err)
+	require.Equal(t, "rot", string(data))
+
+	files, err := filepath.Glob(filepath.Join(jedi, "*", "*", ".repair-*"))
+	require.NoError(t, err)
+	require.Empty(t, files)
+
+	require.NoError(t, os.Remove(filepath.Join(jedi, library.ManifestFile)))
+	v, err = l.Verify(ctxtest.Background(), entry, false)
+	require.NoError(t, err)
+	require.True(t, v.NoManifest)
+}
+
+func TestLoader_Worker_backfillManifest(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	amk := new(apiMock)
+	amk.Test(t)
+	defer amk.AssertExpectations(t)
+
+	l := NewLoader(tempDir, amk, zap.NewNop().Sugar(), OptCategories(CategoryEbook))
+
+	// the book is downloaded before the manifests were introduced.
+	jedi := filepath.Join(tempDir, "00001 Джедайские техники")
+	epub := filepath.Join(jedi, "e-book/epub/Джедайские техники.epub")
+	writeFile(t, epub, 10)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(jedi, library.LockFile), nil, 0644))
+
+	bk := book.Book{
+		ID:    1,
+		Title: "Джедайские техники",
+		Files: book.Files{Books: map[string]book.Addresses{
+			"epub": {{URL: "https://epub", Size: 20}},
+		}},
+	}
+	run := func() {
+		ch := make(chan book.Book, 1)
+		ch <- bk
+		close(ch)
+		require.NoError(t, l.Worker(ctxtest.Background(), ch))
+	}
+	run()
+
+	entry, err := library.Read(jedi)
+	require.NoError(t, err)
+
+	// the current contents are recorded, only the size from the catalog
+	// reveals the damage.
+	v, err := l.Verify(ctxtest.Background(), entry, false)
+	require.NoError(t, err)
+	require.False(t, v.NoManifest)
+	require.Equal(t, []Damage{{Path: "e-book/epub/Джедайские техники.epub", Problem: ProblemSize}}, v.Damages)
+
+	// the manifest is not rewritten by the next runs.
+	require.NoError(t, ioutil.WriteFile(epub, []byte("rotten"), 0644))
+	run()
+
+	v, err = l.Verify(ctxtest.Background(), entry, false)
+	require.NoError(t, err)
+	require.Equal(t, []Damage{{Path: "e-book/epub/Джедайские техники.epub", Problem: ProblemCorrupt}}, v.Damages)
+}
@@ -0,0 +1,363 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/xorcare/miflib.go/internal/book"
+	"github.com/xorcare/miflib.go/internal/library"
+)
+
+// errTargetExists it's the error of the move to the already existing path.
+var errTargetExists = errors.New("target already exists")
+
+// Move it's a relocation of a book directory or a file made by the migration.
+type Move struct {
+	From string
+	To   string
+	// Err is the reason why the move was not made.
+	Err error
+}
+
+// String implements the fmt.Stringer interface.
+func (m Move) String() string {
+	if m.Err != nil {
+		return fmt.Sprintf("conflict %q -> %q: %v", m.From, m.To, m.Err)
+	}
+	return fmt.Sprintf("move %q -> %q", m.From, m.To)
+}
+
+// Migrate moves the books of the library from the places defined by the
+// previous layout to the places defined by the layout of the loader.
+//
+// The files of the books are searched by the previous layout, if a file
+// is not found there, it's searched in the book directory by the format
+// and the size from the catalog entry, so the files named by an earlier
+// version of the naming rules can be found too. The moves to the paths
+// that are already occupied are reported as conflicts and are not made.
+// The video files are moved by the paths recorded in the VideosFile. The
+// books which are placed by the layout into the same directory fail the
+// migration before anything is moved. If dryRun is true, the moves are
+// only reported.
+func (l *Loader) Migrate(from Layout, dryRun bool) ([]Move, error) {
+	entries, err := library.Scan(l.root)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := l.checkTargets(entries); err != nil {
+		return nil, err
+	}
+
+	var moves []Move
+	for _, entry := range entries {
+		ms, err := l.migrateBook(entry, from, dryRun)
+		if err != nil {
+			return moves, err
+		}
+		moves = append(moves, ms...)
+	}
+
+	return moves, nil
+}
+
+// checkTargets checks that the layout places every book of the library into
+// its own directory.
+func (l *Loader) checkTargets(entries []library.Entry) error {
+	bks := make([]book.Book, 0, len(entries))
+	targets := make(map[string]int, len(entries))
+	for _, entry := range entries {
+		bks = append(bks, entry.Book)
+
+		bookpath, err := l.layout.bookPath(l.root, entry.Book)
+		if err != nil {
+			return err
+		}
+		// the directories which differ only by case are the same on the
+		// case-insensitive file systems.
+		key := strings.ToLower(bookpath)
+		if id, ok := targets[key]; ok {
+			return fmt.Errorf("downloader: the layout places the books %d and %d into the same directory %q",
+				id, entry.Book.ID, bookpath)
+		}
+		targets[key] = entry.Book.ID
+	}
+
+	return l.layout.Validate(bks)
+}
+
+func (l *Loader) migrateBook(entry library.Entry, from Layout, dryRun bool) ([]Move, error) {
+	bk := entry.Book
+	bookpath, err := l.layout.bookPath(l.root, bk)
+	if err != nil {
+		return nil, err
+	}
+
+	oldpath := entry.Path
+
+	var moves []Move
+	if !sameDir(oldpath, bookpath) {
+		move := Move{From: oldpath, To: bookpath}
+		if _, err := os.Lstat(bookpath); err == nil {
+			move.Err = errTargetExists
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		} else if strings.HasPrefix(bookpath, oldpath+string(filepath.Separator)) {
+			move.Err = errors.New("target is inside the source directory")
+		}
+
+		if move.Err == nil && !dryRun {
+			if move.Err = rename(oldpath, bookpath); move.Err == nil {
+				removeEmptyDirs(filepath.Dir(oldpath), filepath.Clean(l.root))
+			}
+		}
+		moves = append(moves, move)
+		l.log.Debugw("move the book", "move", move.String())
+
+		if move.Err != nil {
+			return moves, nil
+		}
+		if !dryRun {
+			oldpath = bookpath
+		}
+	}
+
+	files, err := l.migrateFiles(bk, from, entry.Path, oldpath, bookpath)
+	if err != nil {
+		return nil, err
+	}
+	videos, err := l.migrateVideos(bk, oldpath, bookpath)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, videos...)
+
+	renamed := make(map[string]string, len(files))
+	for _, file := range files {
+		move := Move{
+			From: filepath.Join(oldpath, filepath.FromSlash(file.from)),
+			To:   filepath.Join(bookpath, filepath.FromSlash(file.to)),
+		}
+		target := filepath.Join(oldpath, filepath.FromSlash(file.to))
+		if info, err := os.Lstat(target); err == nil {
+			if src, err := os.Lstat(move.From); err != nil || !os.SameFile(src, info) {
+				move.Err = errTargetExists
+			}
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+
+		if move.Err == nil && !dryRun {
+			if move.Err = rename(move.From, move.To); move.Err == nil {
+				removeEmptyDirs(filepath.Dir(move.From), bookpath)
+				renamed[file.from] = file.to
+			}
+		}
+		moves = append(moves, move)
+		l.log.Debugw("move the book", "move", move.String())
+	}
+
+	if err := renameManifest(bookpath, renamed); err != nil {
+		return nil, err
+	}
+	if err := renameVideos(bookpath, renamed); err != nil {
+		return nil, err
+	}
+
+	return moves, nil
+}
+
+// fileMove it's a relocation of a file inside the book directory.
+type fileMove struct {
+	from string
+	to   string
+}
+
+// migrateFiles searches the files of the book placed by the previous layout
+// into the directory frompath in its current location oldpath and returns
+// the files whose paths are changed by the layout of the loader with the
+// book directory bookpath.
+func (l *Loader) migrateFiles(bk book.Book, from Layout, frompath, oldpath, bookpath string) ([]fileMove, error) {
+	items := addressItems(bk)
+
+	oldNames, err := from.fileNames(frompath, bk)
+	if err != nil {
+		return nil, err
+	}
+	newNames, err := l.layout.fileNames(bookpath, bk)
+	if err != nil {
+		return nil, err
+	}
+
+	// claimed it's the files already matched with the items.
+	claimed := map[string]bool{}
+	found := make([]string, len(items))
+	for i, it := range items {
+		name := oldNames[it.key()]
+		if info, err := os.Stat(filepath.Join(oldpath, filepath.FromSlash(name))); err == nil && !info.IsDir() {
+			found[i], claimed[name] = name, true
+		}
+	}
+
+	var files []string
+	for i, it := range items {
+		if found[i] != "" || it.address.Size == 0 {
+			continue
+		}
+		if files == nil {
+			if files, err = listFiles(oldpath); err != nil {
+				return nil, err
+			}
+		}
+		found[i] = matchBySize(oldpath, files, it, claimed)
+		claimed[found[i]] = found[i] != ""
+	}
+
+	var moves []fileMove
+	for i, it := range items {
+		if found[i] == "" {
+			continue
+		}
+		if name := newNames[it.key()]; name != found[i] {
+			moves = append(moves, fileMove{from: found[i], to: name})
+		}
+	}
+
+	return moves, nil
+}
+
+// migrateVideos returns the video files listed in the VideosFile of the
+// book in its current location oldpath whose paths are changed by the
+// layout of the loader with the book directory bookpath.
+func (l *Loader) migrateVideos(bk book.Book, oldpath, bookpath string) ([]fileMove, error) {
+	videos, err := readVideos(oldpath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	names := l.layout.videoNames(bookpath, bk.Videos)
+	byURL := make(map[string]string, len(names))
+	for i, ad := range bk.Videos {
+		if ad.URL != "" {
+			byURL[ad.URL] = names[i]
+		}
+	}
+
+	var moves []fileMove
+	for _, v := range videos {
+		name, ok := byURL[v.URL]
+		if !ok || v.File == "" || v.File == name {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(oldpath, filepath.FromSlash(v.File))); err == nil {
+			moves = append(moves, fileMove{from: v.File, to: name})
+		}
+	}
+
+	return moves, nil
+}
+
+// matchBySize returns the only unclaimed file with the format and the size
+// of the item.
+func matchBySize(bookpath string, files []string, it item, claimed map[string]bool) string {
+	var match string
+	for _, name := range files {
+		if claimed[name] || !strings.EqualFold(path.Ext(name), "."+it.format) {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(bookpath, filepath.FromSlash(name)))
+		if err != nil || info.Size() != int64(it.address.Size) {
+			continue
+		}
+		if match != "" {
+			return ""
+		}
+		match = name
+	}
+
+	return match
+}
+
+// listFiles returns slash separated paths of the regular files of the book
+// directory except the service files.
+func listFiles(bookpath string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(bookpath, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if name == bookpath {
+			return nil
+		}
+		if strings.HasPrefix(info.Name(), ".") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Mode().IsRegular() {
+			rel, err := filepath.Rel(bookpath, name)
+			if err != nil {
+				return err
+			}
+			if rel != library.BookFile && rel != library.ManifestFile {
+				files = append(files, filepath.ToSlash(rel))
+			}
+		}
+		return nil
+	})
+
+	return files, err
+}
+
+// rename moves the file or the directory creating missing parent directories.
+func rename(from, to string) error {
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return err
+	}
+
+	return os.Rename(from, to)
+}
+
+// removeEmptyDirs removes the empty directories starting from dir up to
+// the root exclusive.
+func removeEmptyDirs(dir, root string) {
+	for dir != root && strings.HasPrefix(dir, root) {
+		if infos, err := ioutil.ReadDir(dir); err != nil || len(infos) > 0 {
+			return
+		}
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// sameDir reports whether the paths point to the same directory.
+func sameDir(a, b string) bool {
+	if filepath.Clean(a) == filepath.Clean(b) {
+		return true
+	}
+
+	ai, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bi, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+
+	return os.SameFile(ai, bi)
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package downloader
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/xorcare/miflib.go/internal/book"
+	"github.com/xorcare/miflib.go/internal/history"
+	"github.com/xorcare/miflib.go/internal/library"
+	"github.com/xorcare/miflib.go/internal/translit"
+)
+
+func TestLoader_Migrate(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	bk := book.Book{
+		ID:      42,
+		Title:   "Джедайские техники",
+		Authors: []book.Author{{Name: "Максим Дорофеев"}},
+		Files: book.Files{
+			AudioBooks: book.Formats{
+				"mp3": {
+					{URL: "https://mp3/0", Title: "Введение", Size: 1},
+					{URL: "https://mp3/1", Title: "Глава 1", Size: 2},
+				},
+			},
+			Books: book.Formats{
+				"epub": {{URL: "https://epub", Size: 3}},
+			},
+		},
+	}
+
+	oldpath := filepath.Join(tempDir, "00042 Джедайские техники")
+	writeFile(t, filepath.Join(oldpath, "audiobook/mp3/Введение.mp3"), 1)
+	// the name given by an earlier version of the naming rules.
+	writeFile(t, filepath.Join(oldpath, "audiobook/mp3/Глава 1!.mp3"), 2)
+	writeFile(t, filepath.Join(oldpath, "large.png"), 4)
+	writeFile(t, filepath.Join(oldpath, library.LockFile), 0)
+	_, err = history.Record(oldpath, bk)
+	require.NoError(t, err)
+
+	// the unknown file is in the new place of the epub.
+	writeFile(t, filepath.Join(oldpath, "e-book/epub/Джедайские техники.epub"), 3)
+	writeFile(t, filepath.Join(oldpath, "e-book/01 Джедайские техники.epub"), 5)
+
+	to, err := NewLayout("{{.Author}}/{{.Title}}", "{{.Category}}/{{pad 2 .Part}} {{.Title}}.{{.Format}}")
+	require.NoError(t, err)
+
+	bookpath := filepath.Join(tempDir, "Максим Дорофеев/Джедайские техники")
+	l := NewLoader(tempDir, nil, zap.NewNop().Sugar(), OptLayout(to))
+
+	moves, err := l.Migrate(defaultLayout, true)
+	require.NoError(t, err)
+	require.Len(t, moves, 4)
+	require.DirExists(t, oldpath)
+
+	moves, err = l.Migrate(defaultLayout, false)
+	require.NoError(t, err)
+	require.Equal(t, []Move{
+		{From: oldpath, To: bookpath},
+		{
+			From: filepath.Join(bookpath, "audiobook/mp3/Введение.mp3"),
+			To:   filepath.Join(bookpath, "audiobook/01 Введение.mp3"),
+		},
+		{
+			From: filepath.Join(bookpath, "audiobook/mp3/Глава 1!.mp3"),
+			To:   filepath.Join(bookpath, "audiobook/02 Глава 1.mp3"),
+		},
+		{
+			From: filepath.Join(bookpath, "e-book/epub/Джедайские техники.epub"),
+			To:   filepath.Join(bookpath, "e-book/01 Джедайские техники.epub"),
+			Err:  errTargetExists,
+		},
+	}, moves)
+
+	require.NoDirExists(t, oldpath)
+	require.NoDirExists(t, filepath.Join(bookpath, "audiobook/mp3"))
+	require.FileExists(t, filepath.Join(bookpath, "large.png"))
+	require.FileExists(t, filepath.Join(bookpath, library.LockFile))
+	require.FileExists(t, filepath.Join(bookpath, "e-book/epub/Джедайские техники.epub"))
+
+	entry, err := library.Find(tempDir, 42)
+	require.NoError(t, err)
+	require.Equal(t, bookpath, entry.Path)
+	require.True(t, entry.Downloaded)
+
+	moves, err = l.Migrate(defaultLayout, false)
+	require.NoError(t, err)
+	require.Len(t, moves, 1, "only the conflict remains")
+}
+
+func TestLoader_Migrate_videos(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	bk := book.Book{
+		ID:     42,
+		Title:  "Джедайские техники",
+		Videos: []book.Address{{URL: "https://videos/1.mp4", Title: "Интервью"}},
+	}
+	oldpath := filepath.Join(tempDir, "00042 Джедайские техники")
+	writeFile(t, filepath.Join(oldpath, "videos/Интервью.mp4"), 1)
+	require.NoError(t, writeVideos(oldpath, []video{{URL: "https://videos/1.mp4", File: "videos/Интервью.mp4"}}))
+	_, err = history.Record(oldpath, bk)
+	require.NoError(t, err)
+
+	simple, err := translit.Lookup(translit.Simple)
+	require.NoError(t, err)
+	l := NewLoader(tempDir, nil, zap.NewNop().Sugar(), OptLayout(defaultLayout.WithProfile(Profile{}.WithTranslit(simple))))
+
+	bookpath := filepath.Join(tempDir, "00042 Dzhedayskie tekhniki")
+	moves, err := l.Migrate(defaultLayout, false)
+	require.NoError(t, err)
+	require.Equal(t, []Move{
+		{From: oldpath, To: bookpath},
+		{From: filepath.Join(bookpath, "videos/Интервью.mp4"), To: filepath.Join(bookpath, "videos/Intervyu.mp4")},
+	}, moves)
+	require.FileExists(t, filepath.Join(bookpath, "videos/Intervyu.mp4"))
+
+	videos, err := readVideos(bookpath)
+	require.NoError(t, err)
+	require.Equal(t, []video{{URL: "https://videos/1.mp4", File: "videos/Intervyu.mp4"}}, videos)
+}
+
+func TestLoader_Migrate_conflict(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	author := []book.Author{{Name: "Максим Дорофеев"}}
+	for _, bk := range []book.Book{
+		{ID: 1, Title: "Джедайские техники", Authors: author},
+		{ID: 2, Title: "Путь джедая", Authors: author},
+	} {
+		bookpath, err := defaultLayout.bookPath(tempDir, bk)
+		require.NoError(t, err)
+		require.NoError(t, os.MkdirAll(bookpath, 0755))
+		_, err = history.Record(bookpath, bk)
+		require.NoError(t, err)
+	}
+
+	to, err := NewLayout("{{.Author}}", DefaultFileTemplate)
+	require.NoError(t, err)
+	l := NewLoader(tempDir, nil, zap.NewNop().Sugar(), OptLayout(to))
+
+	for _, dryRun := range []bool{true, false} {
+		moves, err := l.Migrate(defaultLayout, dryRun)
+		require.EqualError(t, err, `downloader: the layout places the books 1 and 2 into the same directory `+
+			fmt.Sprintf("%q", filepath.Join(tempDir, "Максим Дорофеев")))
+		require.Empty(t, moves)
+	}
+	require.DirExists(t, filepath.Join(tempDir, "00001 Джедайские техники"))
+	require.DirExists(t, filepath.Join(tempDir, "00002 Путь джедая"))
+	require.NoDirExists(t, filepath.Join(tempDir, "Максим Дорофеев"))
+}
+
+func writeFile(t *testing.T, filename string, size int) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(filename), 0755))
+	require.NoError(t, ioutil.WriteFile(filename, make([]byte, size), 0644))
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package downloader
+
+import (
+	"github.com/xorcare/miflib.go/internal/ignore"
+)
+
+// Option it's a interface for options func.
+type Option func(*Loader)
+
+// OptLayout it's option for set layout of the library.
+func OptLayout(layout Layout) Option {
+	return func(loader *Loader) {
+		loader.layout = layout
+	}
+}
+
+// OptCategories it's option for set categories of the materials to download.
+func OptCategories(categories ...Category) Option {
+	return func(loader *Loader) {
+		loader.categories = append([]Category{}, categories...)
+	}
+}
+
+// OptFormatRule it's option for set rule of the formats of the category.
+func OptFormatRule(category Category, rule FormatRule) Option {
+	return func(loader *Loader) {
+		formats := make(map[Category]FormatRule, len(loader.formats)+1)
+		for c, r := range loader.formats {
+			formats[c] = r
+		}
+		formats[category] = rule
+		loader.formats = formats
+	}
+}
+
+// OptIgnore it's option for set rules of the ignore file of the library.
+func OptIgnore(rules ignore.Rules) Option {
+	return func(loader *Loader) {
+		loader.ignore = rules
+	}
+}
+
+// OptPlan it's option for the dry run, the books and the files are
+// recorded to the plan instead of downloading.
+func OptPlan(plan *Plan) Option {
+	return func(loader *Loader) {
+		loader.plan = plan
+	}
+}
+
+// OptFreeSpaceReserve it's option for set the free space of the library in
+// bytes below which the downloading is paused.
+func OptFreeSpaceReserve(reserve uint64) Option {
+	return func(loader *Loader) {
+		loader.reserve = reserve
+	}
+}
+
+// OptQuota it's option for set the budget of the downloaded bytes, the
+// quota may be shared by several loaders.
+func OptQuota(quota *Quota) Option {
+	return func(loader *Loader) {
+		loader.quota = quota
+	}
+}
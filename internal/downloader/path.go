@@ -1,16 +1,187 @@
 package downloader
 
 import (
+	"fmt"
 	"path/filepath"
 	"strings"
 
 	"github.com/xorcare/miflib.go/internal/norm"
+	"github.com/xorcare/miflib.go/internal/translit"
 )
 
+// Names of the file system profiles.
+const (
+	// ProfilePosix allows everything except the slash.
+	ProfilePosix = "posix"
+	// ProfileMacOS additionally forbids the colon.
+	ProfileMacOS = "macos"
+	// ProfileWindows forbids the characters not allowed by NTFS and FAT,
+	// the reserved device names and the trailing dots and spaces.
+	ProfileWindows = "windows"
+	// ProfileOneDrive additionally forbids the characters not allowed by
+	// OneDrive for business and SharePoint.
+	ProfileOneDrive = "onedrive"
+	// ProfilePortable it's the union of all profiles, the names are valid
+	// on any of the supported file systems.
+	ProfilePortable = "portable"
+)
+
+// Profile it's the rules of the file names of a target file system.
+type Profile struct {
+	name     string
+	limits   limits
+	replacer *strings.Replacer
+	// windows enables the rules of the Windows file names, the control
+	// characters, the reserved device names and the trailing dots and
+	// spaces are not allowed.
+	windows bool
+	// translit transliterates the Cyrillic letters of the names.
+	translit translit.Scheme
+}
+
+var profiles = map[string]Profile{
+	ProfilePosix:    newProfile(ProfilePosix, posixLimits, posixChars, false),
+	ProfileMacOS:    newProfile(ProfileMacOS, macosLimits, macosChars, false),
+	ProfileWindows:  newProfile(ProfileWindows, windowsLimits, windowsChars, true),
+	ProfileOneDrive: newProfile(ProfileOneDrive, onedriveLimits, append(windowsChars, onedriveChars...), true),
+	ProfilePortable: portable,
+}
+
+// portable it's the profile used by default, the path limit of Windows
+// is not applied to it, since modern versions of Windows support long
+// paths, use the windows profile to fit the paths into MAX_PATH.
+var portable = newProfile(ProfilePortable, macosLimits, forbiddenChars, true)
+
+func newProfile(name string, lim limits, chars []string, windows bool) Profile {
+	oldnew := make([]string, 0, len(chars)*2)
+	for _, char := range unique(chars) {
+		oldnew = append(oldnew, char, "")
+	}
+
+	return Profile{
+		name:     name,
+		limits:   lim,
+		replacer: strings.NewReplacer(oldnew...),
+		windows:  windows,
+	}
+}
+
+// LookupProfile returns the file system profile by the name.
+func LookupProfile(name string) (Profile, error) {
+	profile, ok := profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("downloader: unknown file system profile %q", name)
+	}
+
+	return profile, nil
+}
+
+// Name returns the name of the profile.
+func (p Profile) Name() string {
+	return p.rules().name
+}
+
+// WithTranslit returns the copy of the profile which transliterates the
+// Cyrillic letters of the names by the scheme before clearing them.
+func (p Profile) WithTranslit(scheme translit.Scheme) Profile {
+	p = p.rules()
+	p.translit = scheme
+	return p
+}
+
+// rules returns the profile itself or the portable profile if the profile
+// is not initialized.
+func (p Profile) rules() Profile {
+	if p.replacer == nil {
+		return portable
+	}
+	return p
+}
+
+// clean removes from the file name the characters forbidden by the profile.
+func (p Profile) clean(s string) string {
+	if p.replacer == nil {
+		return portable.clean(s)
+	}
+
+	old := s
+	s = p.translit.String(s)
+	s = norm.String(s)
+	s = p.replacer.Replace(s)
+	if p.windows {
+		s = strings.Map(dropControl, s)
+		s = strings.ReplaceAll(s, " .", ".")
+		s = strings.TrimRight(s, ". ")
+		s = escapeReserved(s)
+	}
+	s = norm.String(s)
+	if s == "." || s == ".." {
+		s = ""
+	}
+
+	if old == s {
+		return s
+	}
+
+	return p.clean(s)
+}
+
+// clearBase clears the base name of the path.
+func (p Profile) clearBase(s string) string {
+	return strings.ReplaceAll(s, filepath.Base(s), p.clean(filepath.Base(s)))
+}
+
+// fitFile truncates the base name of the file to the limits of the profile.
+func (p Profile) fitFile(filename string) string {
+	lim := p.rules().limits
+	dir, base := filepath.Split(filename)
+	return filepath.Join(dir, lim.fit(dir, base, lim.path))
+}
+
 // https://en.wikipedia.org/wiki/Filename
 // https://support.microsoft.com/en-us/office/invalid-file-names-and-file-types-in-onedrive-and-sharepoint-64883a5d-228e-48f5-b3d2-eb39e07630fa
 // https://docs.microsoft.com/en-us/windows/win32/fileio/naming-a-file
 
+var posixChars = []string{
+	"\x00", // null
+	`/`,    // forward slash
+}
+
+var macosChars = []string{
+	"\x00", // null
+	`/`,    // forward slash
+	`:`,    // colon
+}
+
+var windowsChars = []string{
+	`"`, // double quote
+	`*`, // asterisk
+	`/`, // forward slash
+	`:`, // colon
+	`<`, // less than
+	`>`, // greater than
+	`?`, // question mark
+	`\`, // backslash
+	`|`, // vertical bar or pipe
+}
+
+var onedriveChars = []string{
+	`~`, // swung dash or tilde
+	`{`, // opening braces
+	`}`, // closing curly brackets
+}
+
+// reservedNames it's the names of devices reserved by Windows, they are not
+// allowed even with an extension.
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// forbiddenChars it's the characters forbidden by the portable profile.
 var forbiddenChars = unique([]string{
 	// FAT12, FAT16, FAT32
 	`!`, // exclamation mark
@@ -78,26 +249,30 @@ var forbiddenChars = unique([]string{
 	`}`, // closing curly brackets
 })
 
-// replacer it's replaces the specified characters with nothing.
-var replacer = strings.NewReplacer(append(strings.Split(strings.Join(forbiddenChars, "  "), " "), "")...)
-
+// clearBaseName clears the file name by the portable profile.
 func clearBaseName(s string) string {
-	old := s
-	s = norm.String(s)
-	s = replacer.Replace(s)
-	s = strings.ReplaceAll(s, " .", ".")
-	s = strings.TrimSuffix(s, ".")
-	s = norm.String(s)
+	return portable.clean(s)
+}
 
-	if old == s {
-		return s
+// dropControl drops the control characters.
+func dropControl(r rune) rune {
+	if r < 0x20 || r == 0x7f {
+		return -1
 	}
-
-	return clearBaseName(s)
+	return r
 }
 
-func clearBase(s string) string {
-	return strings.ReplaceAll(s, filepath.Base(s), clearBaseName(filepath.Base(s)))
+// escapeReserved appends the underscore to the reserved device names.
+func escapeReserved(s string) string {
+	stem := s
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		stem = s[:i]
+	}
+	if !reservedNames[strings.ToUpper(strings.TrimSpace(stem))] {
+		return s
+	}
+
+	return stem + "_" + s[len(stem):]
 }
 
 func unique(s []string) []string {
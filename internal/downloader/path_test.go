@@ -6,6 +6,9 @@ import (
 
 	"github.com/stretchr/testify/require"
 	"github.com/xorcare/golden"
+
+	"github.com/xorcare/miflib.go/internal/book"
+	"github.com/xorcare/miflib.go/internal/translit"
 )
 
 func Test_clearBaseName(t *testing.T) {
@@ -44,3 +47,72 @@ func Test_clearBaseName(t *testing.T) {
 		})
 	}
 }
+
+func TestProfile_clean(t *testing.T) {
+	const title = `Как {не} сойти с ума: 50% успеха! @ ~ "CON"? `
+	tests := map[string]map[string]string{
+		ProfilePosix: {
+			title:           `Как {не} сойти с ума: 50% успеха! @ ~ "CON"?`,
+			"Что дальше...": "Что дальше...",
+			"a/b\x00":       "ab",
+			"..":            "",
+		},
+		ProfileMacOS: {
+			title: `Как {не} сойти с ума 50% успеха! @ ~ "CON"?`,
+		},
+		ProfileWindows: {
+			title:           `Как {не} сойти с ума 50% успеха! @ ~ CON`,
+			"Что дальше...": "Что дальше",
+			"con":           "con_",
+			"Com1.txt":      "Com1_.txt",
+			"CONSOLE.txt":   "CONSOLE.txt",
+			"a\\b":          "ab",
+		},
+		ProfileOneDrive: {
+			title: `Как не сойти с ума 50% успеха! @ CON`,
+		},
+		ProfilePortable: {
+			title:   `Как не сойти с ума 50 успеха CON`,
+			"nul.":  "nul_",
+			"LPT9 ": "LPT9_",
+		},
+	}
+
+	for name, cases := range tests {
+		profile, err := LookupProfile(name)
+		require.NoError(t, err)
+		require.Equal(t, name, profile.Name())
+		for arg, want := range cases {
+			t.Run(name+"/"+arg, func(t *testing.T) {
+				require.Equal(t, want, profile.clean(arg))
+			})
+		}
+	}
+
+	t.Run("default", func(t *testing.T) {
+		require.Equal(t, ProfilePortable, Profile{}.Name())
+		require.Equal(t, "успеха", Profile{}.clean("успеха!"))
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		_, err := LookupProfile("fat")
+		require.Error(t, err)
+	})
+}
+
+func TestProfile_WithTranslit(t *testing.T) {
+	gost, err := translit.Lookup(translit.GOST)
+	require.NoError(t, err)
+
+	profile := Profile{}.WithTranslit(gost)
+	require.Equal(t, ProfilePortable, profile.Name())
+	require.Equal(t, "Kak ne sojti s uma 50 uspexa", profile.clean(`Как {не} сойти с ума: 50% успеха!`))
+
+	simple, err := translit.Lookup(translit.Simple)
+	require.NoError(t, err)
+
+	layout := defaultLayout.WithProfile(profiles[ProfileWindows].WithTranslit(simple))
+	dir, err := layout.BookDir(book.Book{ID: 42, Title: "Джедайские техники"})
+	require.NoError(t, err)
+	require.Equal(t, "00042 Dzhedayskie tekhniki", dir)
+}
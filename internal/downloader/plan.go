@@ -0,0 +1,285 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/xorcare/miflib.go/internal/book"
+	"github.com/xorcare/miflib.go/internal/library"
+	"github.com/xorcare/miflib.go/internal/osutil"
+)
+
+// Status it's the planned action for the book or the file.
+type Status string
+
+// Statuses of the plan.
+const (
+	// StatusNew it's the book or the file which is missing on the disk.
+	StatusNew Status = "new"
+	// StatusUpdated it's the book or the file which is on the disk but
+	// differs from the catalog.
+	StatusUpdated Status = "updated"
+	// StatusSkipped it's the book or the file which would not be
+	// downloaded.
+	StatusSkipped Status = "skipped"
+)
+
+// PlannedFile it's the file which would be downloaded by the loader.
+type PlannedFile struct {
+	URL      string
+	Filename string
+	// Size is the size of the file from the catalog, zero if unknown.
+	Size   int64
+	Status Status
+}
+
+// PlannedBook it's the book which would be processed by the loader.
+type PlannedBook struct {
+	ID    int
+	Title string
+	Path  string
+	// Reason is the reason why the whole book is skipped.
+	Reason string
+	Files  []PlannedFile
+
+	exists bool
+}
+
+// Status returns the planned action for the book.
+func (b PlannedBook) Status() Status {
+	switch {
+	case b.Reason != "":
+		return StatusSkipped
+	case !b.exists:
+		return StatusNew
+	}
+	for _, f := range b.Files {
+		if f.Status != StatusSkipped {
+			return StatusUpdated
+		}
+	}
+	return StatusSkipped
+}
+
+// Bytes returns the number of bytes which would be downloaded for the
+// book, the files of unknown size are not counted.
+func (b PlannedBook) Bytes() (n int64) {
+	for _, f := range b.Files {
+		if f.Status != StatusSkipped {
+			n += f.Size
+		}
+	}
+	return n
+}
+
+// Plan it's the result of the dry run of the loader, it collects the books
+// and the files instead of downloading them. The plan is safe for
+// concurrent use by the workers.
+type Plan struct {
+	mu    sync.Mutex
+	books []*PlannedBook
+}
+
+// NewPlan creates an empty plan.
+func NewPlan() *Plan {
+	return &Plan{}
+}
+
+// skip records the book which would not be downloaded at all.
+func (p *Plan) skip(bk book.Book, bookpath, reason string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.books = append(p.books, &PlannedBook{ID: bk.ID, Title: bk.Title.String(), Path: bookpath, Reason: reason})
+}
+
+// start records the book which files would be planned.
+func (p *Plan) start(bk book.Book, bookpath string, exists bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.books = append(p.books, &PlannedBook{ID: bk.ID, Title: bk.Title.String(), Path: bookpath, exists: exists})
+}
+
+// add records the file to the book which directory contains the owner.
+func (p *Plan) add(owner string, f PlannedFile) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var found *PlannedBook
+	for _, b := range p.books {
+		if b.Reason != "" || !inside(b.Path, owner) {
+			continue
+		}
+		if found == nil || len(b.Path) > len(found.Path) {
+			found = b
+		}
+	}
+	if found != nil {
+		found.Files = append(found.Files, f)
+	}
+}
+
+func inside(dir, filename string) bool {
+	rel, err := filepath.Rel(dir, filename)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Books returns the books of the plan ordered by the identifiers, the
+// files of every book are ordered by the names.
+func (p *Plan) Books() []PlannedBook {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	books := make([]PlannedBook, 0, len(p.books))
+	for _, b := range p.books {
+		bk := *b
+		bk.Files = append([]PlannedFile(nil), b.Files...)
+		sort.Slice(bk.Files, func(i, j int) bool {
+			return bk.Files[i].Filename < bk.Files[j].Filename
+		})
+		books = append(books, bk)
+	}
+	sort.SliceStable(books, func(i, j int) bool {
+		return books[i].ID < books[j].ID
+	})
+
+	return books
+}
+
+// Bytes returns the number of bytes which would be downloaded for all
+// books of the plan.
+func (p *Plan) Bytes() (n int64) {
+	for _, b := range p.Books() {
+		n += b.Bytes()
+	}
+	return n
+}
+
+// Estimate returns the plan of the books without downloading them, the
+// books are resolved the same way as in the dry run.
+func (l *Loader) Estimate(ctx context.Context, bks []book.Book) (*Plan, error) {
+	est := *l
+	est.log = nopLogger{}
+	est.plan = NewPlan()
+	est.quota = nil
+	est.photos = &photos{done: make(map[string]*photo)}
+
+	ch := make(chan book.Book, len(bks))
+	for _, bk := range bks {
+		ch <- bk
+	}
+	close(ch)
+
+	if err := est.Worker(ctx, ch); err != nil {
+		return nil, err
+	}
+
+	return est.plan, nil
+}
+
+// nopLogger it's the logger which discards the messages.
+type nopLogger struct{}
+
+func (nopLogger) Infow(string, ...interface{})  {}
+func (nopLogger) Debugw(string, ...interface{}) {}
+func (nopLogger) Warnw(string, ...interface{})  {}
+
+// Write prints the plan, the paths are printed relative to the root of the
+// library.
+func (p *Plan) Write(w io.Writer, root string) error {
+	rel := func(name string) string {
+		if r, err := filepath.Rel(root, name); err == nil {
+			return filepath.ToSlash(r)
+		}
+		return name
+	}
+
+	var total int64
+	count := map[Status]int{}
+	for _, b := range p.Books() {
+		status := b.Status()
+		count[status]++
+		total += b.Bytes()
+
+		if b.Reason != "" {
+			if _, err := fmt.Fprintf(w, "%-8s %d %q: %s\n", status, b.ID, b.Title, b.Reason); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if _, err := fmt.Fprintf(w, "%-8s %d %q in %q, %d bytes\n", status, b.ID, b.Title, rel(b.Path), b.Bytes()); err != nil {
+			return err
+		}
+		for _, f := range b.Files {
+			size := "unknown size"
+			if f.Size != 0 {
+				size = fmt.Sprintf("%d bytes", f.Size)
+			}
+			if _, err := fmt.Fprintf(w, "  %-8s %s, %s\n", f.Status, rel(f.Filename), size); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "total: %d new, %d updated, %d skipped books, %d bytes to download\n",
+		count[StatusNew], count[StatusUpdated], count[StatusSkipped], total)
+
+	return err
+}
+
+// planBook records the book and its files to the plan instead of
+// downloading them.
+func (l *Loader) planBook(ctx context.Context, bookpath string, bk book.Book) error {
+	if exist, err := osutil.FileExists(path.Join(bookpath, library.LockFile)); err != nil {
+		return err
+	} else if exist {
+		l.plan.skip(bk, bookpath, "downloaded earlier")
+		return nil
+	}
+
+	_, err := os.Stat(bookpath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	l.plan.start(bk, bookpath, err == nil)
+
+	return l.download(ctx, bookpath, bk)
+}
+
+// planFile records the file to the plan of the book which directory
+// contains the owner, the file of unknown size which exists on the disk
+// is considered unchanged, the empty URL has nothing to download.
+func (l *Loader) planFile(owner, filename, fileURL string, size uint) error {
+	if fileURL == "" {
+		return nil
+	}
+
+	status := StatusSkipped
+	info, err := os.Stat(filename)
+	switch {
+	case os.IsNotExist(err):
+		status = StatusNew
+	case err != nil:
+		return err
+	case size != 0 && info.Size() != int64(size):
+		status = StatusUpdated
+	}
+
+	l.plan.add(owner, PlannedFile{URL: fileURL, Filename: filename, Size: int64(size), Status: status})
+
+	return nil
+}
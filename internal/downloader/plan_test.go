@@ -0,0 +1,113 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package downloader
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/xorcare/miflib.go/internal/book"
+	"github.com/xorcare/miflib.go/internal/ctxtest"
+	"github.com/xorcare/miflib.go/internal/ignore"
+	"github.com/xorcare/miflib.go/internal/library"
+)
+
+func TestLoader_Worker_plan(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	amk := new(apiMock)
+	amk.Test(t)
+	defer amk.AssertExpectations(t)
+
+	rules, err := ignore.Parse([]byte("4"))
+	require.NoError(t, err)
+
+	plan := NewPlan()
+	l := NewLoader(tempDir, amk, zap.NewNop().Sugar(),
+		OptCategories(CategoryEbook, CategoryCover), OptIgnore(rules), OptPlan(plan))
+
+	jedi := filepath.Join(tempDir, "00001 Джедайские техники")
+	writeFile(t, filepath.Join(jedi, "e-book/pdf/Джедайские техники.pdf"), 10)
+	writeFile(t, filepath.Join(jedi, "e-book/epub/Джедайские техники.epub"), 7)
+	writeFile(t, filepath.Join(jedi, "small.png"), 3)
+	writeFile(t, filepath.Join(tempDir, "00003 Ёлки", library.LockFile), 0)
+
+	ch := make(chan book.Book, 4)
+	ch <- book.Book{
+		ID:    1,
+		Title: "Джедайские техники",
+		Cover: book.Cover{Small: "https://cover/small.png", Large: "https://cover/large.png"},
+		Files: book.Files{Books: map[string]book.Addresses{
+			"pdf":  {{URL: "https://pdf", Size: 10}},
+			"epub": {{URL: "https://epub", Size: 20}},
+			"fb2":  {{URL: "https://fb2", Size: 30}},
+		}},
+	}
+	ch <- book.Book{
+		ID:    2,
+		Title: "Путь джедая",
+		Files: book.Files{Books: map[string]book.Addresses{"pdf": {{URL: "https://pdf/2", Size: 5}}}},
+	}
+	ch <- book.Book{ID: 3, Title: "Ёлки", Files: book.Files{Books: map[string]book.Addresses{"pdf": {{URL: "https://pdf/3"}}}}}
+	ch <- book.Book{ID: 4, Title: "Игнор"}
+	close(ch)
+
+	require.NoError(t, l.Worker(ctxtest.Background(), ch))
+	require.NoDirExists(t, filepath.Join(tempDir, "00002 Путь джедая"))
+	require.NoFileExists(t, filepath.Join(jedi, library.LockFile))
+
+	buf := bytes.Buffer{}
+	require.NoError(t, plan.Write(&buf, tempDir))
+	require.Equal(t, `updated  1 "Джедайские техники" in "00001 Джедайские техники", 50 bytes
+  updated  00001 Джедайские техники/e-book/epub/Джедайские техники.epub, 20 bytes
+  new      00001 Джедайские техники/e-book/fb2/Джедайские техники.fb2, 30 bytes
+  skipped  00001 Джедайские техники/e-book/pdf/Джедайские техники.pdf, 10 bytes
+  new      00001 Джедайские техники/large.png, unknown size
+  skipped  00001 Джедайские техники/small.png, unknown size
+new      2 "Путь джедая" in "00002 Путь джедая", 5 bytes
+  new      00002 Путь джедая/e-book/pdf/Путь джедая.pdf, 5 bytes
+skipped  3 "Ёлки": downloaded earlier
+skipped  4 "Игнор": ignored by the rule "4"
+total: 1 new, 1 updated, 2 skipped books, 55 bytes to download
+`, buf.String())
+}
+
+func TestLoader_Estimate(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	amk := new(apiMock)
+	amk.Test(t)
+	defer amk.AssertExpectations(t)
+
+	l := NewLoader(tempDir, amk, zap.NewNop().Sugar())
+	plan, err := l.Estimate(ctxtest.Background(), []book.Book{
+		{ID: 1, Title: "Путь джедая", Files: book.Files{Books: map[string]book.Addresses{
+			"pdf":  {{URL: "https://pdf", Size: 5}},
+			"epub": {{URL: "https://epub", Size: 7}},
+		}}},
+		{ID: 2, Title: "Ёлки", Files: book.Files{AudioBooks: map[string]book.Addresses{
+			"mp3": {{URL: "https://mp3/1", Size: 100}, {URL: "https://mp3/2", Size: 200}},
+		}}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(312), plan.Bytes())
+	require.Len(t, plan.Books(), 2)
+	require.Nil(t, l.plan)
+	require.Empty(t, l.photos.done)
+
+	entries, err := ioutil.ReadDir(tempDir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package downloader
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/xorcare/miflib.go/internal/book"
+)
+
+// errQuotaExceeded it's the error of the file which doesn't fit into the
+// quota, the book of the file is left for the next run.
+var errQuotaExceeded = errors.New("downloader: the download quota is exceeded")
+
+// Quota it's the budget of the bytes downloaded during the run, once a file
+// doesn't fit into the budget no more files are downloaded. The quota is
+// safe for concurrent use, the nil quota is unlimited.
+type Quota struct {
+	mu       sync.Mutex
+	limit    uint64
+	used     uint64
+	exceeded bool
+	backlog  []book.Book
+}
+
+// NewQuota creates the quota of the limit of bytes.
+func NewQuota(limit uint64) *Quota {
+	return &Quota{limit: limit}
+}
+
+// take reserves the bytes of the file before the download, it reports
+// whether the file fits into the quota. The reservation is replaced by the
+// bytes actually written by settle after the download.
+func (q *Quota) take(size uint64) bool {
+	if q == nil {
+		return true
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.exceeded || q.used >= q.limit || size > q.limit-q.used {
+		q.exceeded = true
+		return false
+	}
+	q.used += size
+
+	return true
+}
+
+// settle replaces the reserved bytes of the file with the bytes written by
+// the download, the file which is skipped or failed is not counted.
+func (q *Quota) settle(reserved, written uint64) {
+	if q == nil {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.used = q.used - reserved + written
+}
+
+// Exceeded reports whether the quota is reached.
+func (q *Quota) Exceeded() bool {
+	if q == nil {
+		return false
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.exceeded
+}
+
+// Limit returns the limit of the quota in bytes.
+func (q *Quota) Limit() uint64 {
+	return q.limit
+}
+
+// Used returns the number of the bytes counted by the quota.
+func (q *Quota) Used() uint64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.used
+}
+
+// Defer adds the books which are left for the next run to the backlog.
+func (q *Quota) Defer(bks ...book.Book) {
+	if q == nil {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.backlog = append(q.backlog, bks...)
+}
+
+// Backlog returns the books which are left for the next run ordered by the
+// identifiers.
+func (q *Quota) Backlog() []book.Book {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	backlog := append([]book.Book(nil), q.backlog...)
+	sort.Slice(backlog, func(i, j int) bool {
+		return backlog[i].ID < backlog[j].ID
+	})
+
+	return backlog
+}
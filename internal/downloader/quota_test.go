@@ -0,0 +1,127 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package downloader
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/xorcare/miflib.go/internal/api"
+	"github.com/xorcare/miflib.go/internal/book"
+	"github.com/xorcare/miflib.go/internal/ctxtest"
+	"github.com/xorcare/miflib.go/internal/library"
+)
+
+func TestLoader_Worker_quota(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	amk := new(apiMock)
+	amk.Test(t)
+	defer amk.AssertExpectations(t)
+
+	quota := NewQuota(25)
+	l := NewLoader(tempDir, amk, zap.NewNop().Sugar(), OptCategories(CategoryEbook, CategoryCover), OptQuota(quota))
+
+	jedi := filepath.Join(tempDir, "00001 Джедайские техники")
+	amk.On("DownloadFile", ctxtest.Match, "https://pdf/1", filepath.Join(jedi, "e-book/pdf/Джедайские техники.pdf")).
+		Return(nil).Once().Run(func(args mock.Arguments) {
+		writeFile(t, args.String(2), 10)
+	})
+	amk.On("DownloadFile", ctxtest.Match, "https://cover/1.png", filepath.Join(jedi, "1.png")).
+		Return(nil).Once().Run(func(args mock.Arguments) {
+		writeFile(t, args.String(2), 5)
+	})
+	amk.On("DownloadFile", ctxtest.Match, "https://cover/1-small.png", filepath.Join(jedi, "1-small.png")).
+		Return(nil).Once()
+	// the cover of the second book may be downloaded before its ebook
+	// exceeds the quota.
+	amk.On("DownloadFile", ctxtest.Match, "https://cover/2.png", mock.Anything).Return(nil).Maybe()
+
+	bks := []book.Book{
+		{ID: 1, Title: "Джедайские техники", Cover: book.Cover{Large: "https://cover/1.png", Small: "https://cover/1-small.png"},
+			Files: book.Files{Books: map[string]book.Addresses{"pdf": {{URL: "https://pdf/1", Size: 10}}}}},
+		{ID: 2, Title: "Путь джедая", Cover: book.Cover{Large: "https://cover/2.png", Small: "https://cover/2.png"},
+			Files: book.Files{Books: map[string]book.Addresses{"pdf": {{URL: "https://pdf/2", Size: 20}}}}},
+		{ID: 3, Title: "Ёлки", Cover: book.Cover{Large: "https://cover/3.png", Small: "https://cover/3.png"},
+			Files: book.Files{Books: map[string]book.Addresses{"pdf": {{URL: "https://pdf/3", Size: 1}}}}},
+	}
+	ch := make(chan book.Book, len(bks))
+	for _, bk := range bks {
+		ch <- bk
+	}
+	close(ch)
+
+	require.NoError(t, l.Worker(ctxtest.Background(), ch))
+	require.FileExists(t, filepath.Join(jedi, library.LockFile))
+	require.NoFileExists(t, filepath.Join(tempDir, "00002 Путь джедая", library.LockFile))
+	require.True(t, quota.Exceeded())
+	require.Equal(t, uint64(15), quota.Used())
+	require.Equal(t, []book.Book{bks[1], bks[2]}, quota.Backlog())
+}
+
+func TestLoader_fetch_quota(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	amk := new(apiMock)
+	amk.Test(t)
+	defer amk.AssertExpectations(t)
+
+	quota := NewQuota(100)
+	l := NewLoader(tempDir, amk, zap.NewNop().Sugar(), OptQuota(quota))
+	ctx := ctxtest.Background()
+
+	missing := filepath.Join(tempDir, "missing.pdf")
+	amk.On("DownloadFile", ctxtest.Match, "https://missing", missing).Return(&api.Error{Code: 404}).Once()
+	require.NoError(t, l.fetch(ctx, "https://missing", missing, 30))
+	require.Equal(t, uint64(0), quota.Used(), "the undiscovered file is not counted")
+
+	cover := filepath.Join(tempDir, "cover.png")
+	writeFile(t, cover, 7)
+	amk.On("DownloadFile", ctxtest.Match, "https://cover", cover).Return(nil).Once()
+	require.NoError(t, l.fetch(ctx, "https://cover", cover, 0))
+	require.Equal(t, uint64(0), quota.Used(), "the existing file is not counted again")
+
+	pdf := filepath.Join(tempDir, "book.pdf")
+	amk.On("DownloadFile", ctxtest.Match, "https://pdf", pdf).Return(nil).Once().Run(func(args mock.Arguments) {
+		writeFile(t, args.String(2), 12)
+	})
+	require.NoError(t, l.fetch(ctx, "https://pdf", pdf, 20))
+	require.Equal(t, uint64(12), quota.Used(), "the written bytes are counted")
+	require.False(t, quota.Exceeded())
+}
+
+func TestQuota_take(t *testing.T) {
+	var unlimited *Quota
+	require.True(t, unlimited.take(1<<40))
+	require.False(t, unlimited.Exceeded())
+
+	q := NewQuota(10)
+	require.True(t, q.take(4))
+	require.True(t, q.take(6))
+	require.False(t, q.Exceeded())
+	require.False(t, q.take(0))
+	require.True(t, q.Exceeded())
+
+	q = NewQuota(10)
+	require.False(t, q.take(11))
+	require.False(t, q.take(1), "the quota is exceeded by the previous file")
+
+	q = NewQuota(10)
+	require.True(t, q.take(8))
+	q.settle(8, 3)
+	require.Equal(t, uint64(3), q.Used())
+	require.True(t, q.take(7))
+	unlimited.settle(1, 2)
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package downloader
+
+import (
+	"context"
+	"time"
+
+	"github.com/xorcare/miflib.go/internal/osutil"
+)
+
+// freeSpace it's the function returning the free space of the file system,
+// it's replaced in the tests.
+var freeSpace = osutil.FreeSpace
+
+// spaceCheckInterval it's the interval between the checks of the free
+// space while the downloading is paused.
+var spaceCheckInterval = time.Minute
+
+// waitSpace pauses the downloading while the free space of the library
+// left after the download of the file of the size is below the reserve,
+// zero is the unknown size. The checks are disabled on the platforms where
+// the free space can't be determined.
+func (l *Loader) waitSpace(ctx context.Context, size uint64) error {
+	if l.reserve == 0 {
+		return nil
+	}
+
+	for paused := false; ; paused = true {
+		free, err := freeSpace(l.root)
+		if err == osutil.ErrFreeSpaceUnsupported {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if free >= l.reserve && free-l.reserve >= size {
+			if paused {
+				l.log.Infow("resume downloading, the free space is available", "path", l.root, "bytes", free)
+			}
+			return nil
+		}
+
+		if !paused {
+			l.log.Warnw("pause downloading, the free space is below the reserve",
+				"path", l.root, "bytes", free, "reserve", l.reserve, "file_bytes", size)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(spaceCheckInterval):
+		}
+	}
+}
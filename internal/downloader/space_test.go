@@ -0,0 +1,62 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package downloader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/xorcare/miflib.go/internal/osutil"
+)
+
+func TestLoader_waitSpace(t *testing.T) {
+	defer func(f func(string) (uint64, error), interval time.Duration) {
+		freeSpace, spaceCheckInterval = f, interval
+	}(freeSpace, spaceCheckInterval)
+	spaceCheckInterval = time.Millisecond
+
+	var calls []string
+	free := []uint64{10, 50, 100}
+	freeSpace = func(path string) (uint64, error) {
+		calls = append(calls, path)
+		n := free[0]
+		free = free[1:]
+		return n, nil
+	}
+
+	l := NewLoader("library", nil, zap.NewNop().Sugar(), OptFreeSpaceReserve(100))
+	require.NoError(t, l.waitSpace(context.Background(), 0))
+	require.Equal(t, []string{"library", "library", "library"}, calls)
+
+	t.Run("file size", func(t *testing.T) {
+		// the file of 30 bytes fits only into the free space of 130 bytes.
+		free = []uint64{100, 120, 130}
+		calls = nil
+		require.NoError(t, l.waitSpace(context.Background(), 30))
+		require.Len(t, calls, 3)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		freeSpace = func(string) (uint64, error) { return 0, nil }
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		require.Equal(t, context.Canceled, l.waitSpace(ctx, 0))
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		freeSpace = func(string) (uint64, error) { return 0, osutil.ErrFreeSpaceUnsupported }
+		require.NoError(t, l.waitSpace(context.Background(), 0))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		freeSpace = func(string) (uint64, error) { panic("unexpected call") }
+		l := NewLoader("library", nil, zap.NewNop().Sugar())
+		require.NoError(t, l.waitSpace(context.Background(), 0))
+	})
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package downloader
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// limits it's the length limits of the paths of a file system.
+type limits struct {
+	// name is the maximum length of an element of the path.
+	name int
+	// path is the maximum length of the whole path.
+	path int
+	// utf16 enables measuring of the lengths in UTF-16 code units
+	// instead of bytes.
+	utf16 bool
+	// nameBytes is the maximum length of an element of the path in bytes
+	// of the host which mounts the file system, zero disables the limit.
+	nameBytes int
+}
+
+// https://en.wikipedia.org/wiki/Comparison_of_file_systems#Limits
+// https://docs.microsoft.com/en-us/windows/win32/fileio/maximum-file-path-limitation
+var (
+	// posixLimits it's NAME_MAX and PATH_MAX of Linux without the
+	// terminating null character.
+	posixLimits = limits{name: 255, path: 4095}
+	// macosLimits it's NAME_MAX and PATH_MAX of macOS without the
+	// terminating null character.
+	macosLimits = limits{name: 255, path: 1023}
+	// windowsLimits it's MAX_PATH without the terminating null character,
+	// the names are also limited by NAME_MAX of the Linux and macOS hosts
+	// which mount such file systems, for example by SMB.
+	windowsLimits = limits{name: 255, path: 259, utf16: true, nameBytes: 255}
+	// onedriveLimits it's the limit of the decoded path in OneDrive, the
+	// names are limited as the ones of windowsLimits.
+	onedriveLimits = limits{name: 255, path: 400, utf16: true, nameBytes: 255}
+)
+
+// hashSuffixLen it's the length of the suffix added to the truncated names.
+const hashSuffixLen = len("~") + 6
+
+// length returns the length of the string in the units of the limits.
+func (l limits) length(s string) int {
+	if !l.utf16 {
+		return len(s)
+	}
+
+	n := 0
+	for _, r := range s {
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+
+	return n
+}
+
+// fit truncates the elements of the slash separated path rel so that
+// every element fits into the name limit and the path joined with the
+// base directory fits into max. If max is not positive, only the names
+// are limited. The extension of the last element is kept, the truncated
+// elements get a stable hash suffix so that they remain unique.
+func (l limits) fit(base, rel string, max int) string {
+	elems := strings.Split(rel, "/")
+	for i, elem := range elems {
+		elems[i] = l.truncate(elem, l.name, i == len(elems)-1)
+	}
+
+	if max <= 0 {
+		return path.Join(elems...)
+	}
+
+	if abs, err := filepath.Abs(base); err == nil {
+		base = abs
+	}
+	over := l.length(base) - max
+	for _, elem := range elems {
+		over += len(string(filepath.Separator)) + l.length(elem)
+	}
+
+	// the names are shortened starting from the file name, the book and
+	// category directories are shortened only if it's not enough.
+	for i := len(elems) - 1; i >= 0 && over > 0; i-- {
+		size := l.length(elems[i])
+		short := l.truncate(elems[i], size-over, i == len(elems)-1)
+		if l.length(short) >= size {
+			continue
+		}
+		over -= size - l.length(short)
+		elems[i] = short
+	}
+
+	return path.Join(elems...)
+}
+
+// truncate shortens the name to max and to the limit of the bytes at the
+// boundary of the runes keeping the extension if ext is true, a hash of
+// the original name is added to the end of the truncated name.
+func (l limits) truncate(name string, max int, ext bool) string {
+	if l.length(name) <= max && (l.nameBytes == 0 || len(name) <= l.nameBytes) {
+		return name
+	}
+
+	extension := ""
+	if ext {
+		extension = path.Ext(name)
+		if l.length(extension) > max/2 {
+			extension = ""
+		}
+	}
+
+	sum := sha1.Sum([]byte(name))
+	suffix := "~" + hex.EncodeToString(sum[:])[:hashSuffixLen-1] + extension
+
+	stem := strings.TrimSuffix(name, extension)
+	budget := max - l.length(suffix)
+	bytes := len(stem)
+	if l.nameBytes > 0 {
+		bytes = l.nameBytes - len(suffix)
+	}
+
+	n, size := 0, 0
+	for size < len(stem) {
+		r, width := utf8.DecodeRuneInString(stem[size:])
+		if n += l.length(string(r)); n > budget || size+width > bytes {
+			break
+		}
+		size += width
+	}
+
+	return strings.TrimRight(stem[:size], " .") + suffix
+}
+
+// cutter this function is designed to trim file names that are too long
+// due to file system restrictions.
+func cutter(filename string) string {
+	return portable.fitFile(filename)
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package downloader
+
+import (
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimits_fit(t *testing.T) {
+	const root = "/books"
+	title := strings.TrimSpace(strings.Repeat("Джедайские техники ", 10))
+
+	t.Run("names", func(t *testing.T) {
+		short := "Джедайские техники"
+		got := windowsLimits.fit(root, short+"/"+short+".mp3", 0)
+		require.Equal(t, short+"/"+short+".mp3", got)
+
+		got = windowsLimits.fit(root, title+"/"+title+".mp3", 0)
+		elems := strings.Split(got, "/")
+		require.Len(t, elems, 2)
+		require.Equal(t, 189, windowsLimits.length(title))
+		require.Greater(t, len(title), 255)
+		// the name fits into UTF-16 units, but not into NAME_MAX of the
+		// host in bytes.
+		require.LessOrEqual(t, len(elems[0]), 255)
+		require.LessOrEqual(t, len(elems[1]), 255)
+		require.Equal(t, ".mp3", path.Ext(elems[1]))
+
+		got = windowsLimits.fit(root, strings.Repeat("a", 300)+"/"+strings.Repeat("a", 300)+".mp3", 0)
+		elems = strings.Split(got, "/")
+		require.Equal(t, 255, windowsLimits.length(elems[0]))
+		require.Equal(t, 255, windowsLimits.length(elems[1]))
+		require.Equal(t, ".mp3", path.Ext(elems[1]))
+
+		got = posixLimits.fit(root, title+"/"+title+".mp3", 0)
+		elems = strings.Split(got, "/")
+		require.True(t, len(elems[0]) <= 255)
+		require.True(t, len(elems[1]) <= 255)
+	})
+
+	t.Run("path", func(t *testing.T) {
+		got := windowsLimits.fit(root, "audiobook/mp3/"+title+title+".mp3", windowsLimits.path)
+		require.True(t, windowsLimits.length(root+"/"+got) <= windowsLimits.path)
+		require.True(t, strings.HasPrefix(got, "audiobook/mp3/Джедайские техники"))
+		require.Equal(t, ".mp3", path.Ext(got))
+
+		latin := strings.Repeat("Jedi techniques ", 20)
+		got = windowsLimits.fit(root, "audiobook/mp3/"+latin+".mp3", windowsLimits.path)
+		require.True(t, windowsLimits.length(root+"/"+got) <= windowsLimits.path)
+		require.True(t, windowsLimits.length(root+"/"+got) > windowsLimits.path-2)
+
+		// the directories are shortened when the file name is not enough.
+		got = windowsLimits.fit(root, title+"/"+title+"/a.mp3", windowsLimits.path)
+		require.True(t, windowsLimits.length(root+"/"+got) <= windowsLimits.path)
+		require.True(t, strings.HasSuffix(got, "/a.mp3"))
+	})
+
+	t.Run("unique", func(t *testing.T) {
+		a := macosLimits.fit(root, title+title+"1.mp3", 0)
+		b := macosLimits.fit(root, title+title+"2.mp3", 0)
+		require.NotEqual(t, a, b)
+	})
+}
@@ -0,0 +1,252 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package downloader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xorcare/miflib.go/internal/book"
+	"github.com/xorcare/miflib.go/internal/library"
+)
+
+// videosDir it's the directory of the book with the video files.
+const videosDir = "videos"
+
+// videoAttempts it's the number of attempts to download a video file,
+// the video files are large and their downloading is often interrupted.
+const videoAttempts = 3
+
+// retryDelay it's the delay before the second attempt, it's increased
+// with every next attempt.
+var retryDelay = 5 * time.Second
+
+// videoExts it's the extensions of the video files hosted directly, other
+// links are considered links to external players.
+var videoExts = map[string]bool{
+	".3gp": true, ".avi": true, ".flv": true, ".m4v": true, ".mkv": true,
+	".mov": true, ".mp4": true, ".mpeg": true, ".mpg": true, ".ogv": true,
+	".webm": true,
+}
+
+// video it's the entry of the VideosFile.
+type video struct {
+	Title    string `json:"title,omitempty"`
+	Duration string `json:"duration,omitempty"`
+	URL      string `json:"url"`
+	// File is the slash separated path to the downloaded file relative
+	// to the book directory.
+	File string `json:"file,omitempty"`
+	// External reports whether the link leads to an external player, such
+	// videos are not downloaded.
+	External bool `json:"external,omitempty"`
+}
+
+// downloadVideos downloads the directly hosted video files of the book and
+// lists all videos with their titles and durations in the VideosFile.
+func (l *Loader) downloadVideos(ctx context.Context, basepath string, book book.Book) error {
+	if len(book.Videos) == 0 {
+		return nil
+	}
+
+	l.log.Infow("start downloading the videos", "book_id", book.ID, "title", book.Title)
+	defer l.log.Infow("finish downloading the videos", "book_id", book.ID, "title", book.Title)
+
+	names := l.layout.videoNames(basepath, book.Videos)
+	videos := make([]video, 0, len(book.Videos))
+	for i, ad := range book.Videos {
+		if ad.URL == "" {
+			continue
+		}
+
+		v := video{Title: ad.Title.String(), Duration: ad.Duration, URL: ad.URL}
+		if !videoExts[strings.ToLower(urlExt(ad.URL))] {
+			l.log.Infow("record the link to the external player", "book_id", book.ID, "url", ad.URL)
+			v.External = true
+			videos = append(videos, v)
+			continue
+		}
+
+		filename := filepath.Join(basepath, filepath.FromSlash(names[i]))
+		err := retry(ctx, videoAttempts, func(attempt int) error {
+			if attempt > 1 {
+				l.log.Warnw("retry downloading the video", "book_id", book.ID, "url", ad.URL, "attempt", attempt)
+			}
+			return l.downloadVideo(ctx, filename, ad)
+		})
+		if _, ok := err.(*sizeError); ok {
+			// the catalog entry is wrong, it must not stop the download of
+			// the other books.
+			l.log.Warnw("skip the video which size differs from the catalog", "book_id", book.ID,
+				"url", ad.URL, "error", err)
+		} else if err != nil {
+			return err
+		}
+
+		if _, err := os.Stat(filename); err == nil {
+			v.File = names[i]
+		}
+		videos = append(videos, v)
+	}
+
+	if l.plan != nil {
+		return nil
+	}
+
+	return writeVideos(basepath, videos)
+}
+
+// readVideos reads the VideosFile of the book.
+func readVideos(bookpath string) ([]video, error) {
+	data, err := ioutil.ReadFile(filepath.Join(bookpath, library.VideosFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var videos []video
+	if err := json.Unmarshal(data, &videos); err != nil {
+		return nil, fmt.Errorf("downloader: %s of %q: %v", library.VideosFile, bookpath, err)
+	}
+
+	return videos, nil
+}
+
+// writeVideos writes the VideosFile of the book.
+func writeVideos(bookpath string, videos []video) error {
+	data, err := json.MarshalIndent(videos, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(bookpath, library.VideosFile), append(data, '\n'), 0644)
+}
+
+// renameVideos updates the paths of the video files moved inside the book
+// directory in its VideosFile, the book without the videos is skipped.
+func renameVideos(bookpath string, renamed map[string]string) error {
+	if len(renamed) == 0 {
+		return nil
+	}
+
+	videos, err := readVideos(bookpath)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for i, v := range videos {
+		if to, ok := renamed[v.File]; ok && v.File != "" {
+			videos[i].File = to
+		}
+	}
+
+	return writeVideos(bookpath, videos)
+}
+
+// sizeError it's the error of the file which size differs from the size of
+// its address.
+type sizeError struct {
+	filename string
+	size     int64
+	expected uint
+}
+
+func (e *sizeError) Error() string {
+	return fmt.Sprintf("downloader: the size of the file %q is %d, expected %d", e.filename, e.size, e.expected)
+}
+
+// downloadVideo downloads the video file and checks that its size is equal
+// to the size of the address, the file of the other size is removed to be
+// downloaded again by the next attempt.
+func (l *Loader) downloadVideo(ctx context.Context, filename string, ad book.Address) error {
+	if err := l.downloadByAddress(ctx, filename, ad); err != nil || l.plan != nil {
+		return err
+	}
+
+	info, err := os.Stat(filename)
+	if os.IsNotExist(err) {
+		// the file is skipped by downloadFile.
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if ad.Size != 0 && info.Size() != int64(ad.Size) {
+		if err := os.Remove(filename); err != nil {
+			return err
+		}
+		return &sizeError{filename: filename, size: info.Size(), expected: ad.Size}
+	}
+
+	return nil
+}
+
+// videoNames returns the slash separated paths of the video files relative
+// to the book directory, the files are named by the titles of the videos.
+func (l Layout) videoNames(bookpath string, videos []book.Address) []string {
+	lim := l.profile.rules().limits
+	names := make([]string, len(videos))
+	for i, ad := range videos {
+		ext := urlExt(ad.URL)
+		name := l.profile.clean(ad.Title.String())
+		if name == "" {
+			name = l.profile.clean(strings.TrimSuffix(urlBase(ad.URL), ext))
+		}
+		if name == "" {
+			name = strconv.Itoa(i + 1)
+		}
+		names[i] = videosDir + "/" + name + ext
+	}
+
+	for _, i := range collisions(names) {
+		names[i] = withSuffix(names[i], strconv.Itoa(i+1))
+	}
+
+	for i := range names {
+		names[i] = lim.fit(bookpath, names[i], lim.path)
+	}
+
+	return names
+}
+
+// urlBase returns the base name of the file in the path of the URL.
+func urlBase(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return path.Base(u.Path)
+}
+
+// retry calls the function until it succeeds or the attempts run out, the
+// delay between the attempts is increased with every attempt, the exceeded
+// quota is not retried.
+func retry(ctx context.Context, attempts int, f func(attempt int) error) (err error) {
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(retryDelay * time.Duration(attempt-1)):
+			}
+		}
+
+		if err = f(attempt); err == nil || err == errQuotaExceeded || ctx.Err() != nil {
+			return err
+		}
+	}
+
+	return err
+}
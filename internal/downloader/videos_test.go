@@ -0,0 +1,115 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package downloader
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/xorcare/miflib.go/internal/book"
+	"github.com/xorcare/miflib.go/internal/library"
+)
+
+func TestLoader_downloadVideos(t *testing.T) {
+	defer func(delay time.Duration) { retryDelay = delay }(retryDelay)
+	retryDelay = 0
+
+	tempDir, err := ioutil.TempDir("", t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	amk := new(apiMock)
+	amk.Test(t)
+	defer amk.AssertExpectations(t)
+
+	l := NewLoader(tempDir, amk, zap.NewNop().Sugar())
+	ctx := context.Background()
+
+	intro := filepath.Join(tempDir, "videos/Интервью с автором.mp4")
+	amk.On("DownloadFile", ctx, "https://videos/1.mp4", intro).Return(io.ErrUnexpectedEOF).Once()
+	amk.On("DownloadFile", ctx, "https://videos/1.mp4", intro).Return(nil).Once().
+		Run(func(args mock.Arguments) {
+			writeFile(t, args.String(2), 5)
+		})
+	amk.On("DownloadFile", ctx, "https://videos/1.mp4", intro).Return(nil).Once().
+		Run(func(args mock.Arguments) {
+			writeFile(t, args.String(2), 10)
+		})
+	amk.On("DownloadFile", ctx, "https://videos/trailer.webm?v=1", filepath.Join(tempDir, "videos/trailer.webm")).
+		Return(nil).Once().
+		Run(func(args mock.Arguments) {
+			writeFile(t, args.String(2), 3)
+		})
+
+	require.NoError(t, l.downloadVideos(ctx, tempDir, book.Book{
+		Title: "Джедайские техники",
+		Videos: []book.Address{
+			{URL: "https://videos/1.mp4", Title: "Интервью с автором?", Duration: "12:01", Size: 10},
+			{URL: "https://www.youtube.com/embed/xyz", Title: "Презентация", Duration: "01:02:03"},
+			{URL: "https://videos/trailer.webm?v=1"},
+			{},
+		},
+	}))
+
+	data, err := ioutil.ReadFile(filepath.Join(tempDir, library.VideosFile))
+	require.NoError(t, err)
+
+	var videos []video
+	require.NoError(t, json.Unmarshal(data, &videos))
+	require.Equal(t, []video{
+		{
+			Title:    "Интервью с автором?",
+			Duration: "12:01",
+			URL:      "https://videos/1.mp4",
+			File:     "videos/Интервью с автором.mp4",
+		},
+		{
+			Title:    "Презентация",
+			Duration: "01:02:03",
+			URL:      "https://www.youtube.com/embed/xyz",
+			External: true,
+		},
+		{
+			URL:  "https://videos/trailer.webm?v=1",
+			File: "videos/trailer.webm",
+		},
+	}, videos)
+
+	t.Run("size mismatch", func(t *testing.T) {
+		filename := filepath.Join(tempDir, "videos/3.mp4")
+		amk.On("DownloadFile", ctx, "https://videos/3.mp4", filename).Return(nil).Times(videoAttempts).
+			Run(func(args mock.Arguments) {
+				// the bad file of the previous attempt is removed.
+				require.NoFileExists(t, args.String(2))
+				writeFile(t, args.String(2), 7)
+			})
+		require.NoError(t, l.downloadVideos(ctx, tempDir, book.Book{
+			Videos: []book.Address{{URL: "https://videos/3.mp4", Size: 10}},
+		}))
+		require.NoFileExists(t, filename)
+
+		data, err := ioutil.ReadFile(filepath.Join(tempDir, library.VideosFile))
+		require.NoError(t, err)
+		require.JSONEq(t, `[{"url": "https://videos/3.mp4"}]`, string(data))
+	})
+
+	t.Run("error", func(t *testing.T) {
+		amk.On("DownloadFile", ctx, "https://videos/2.mp4", filepath.Join(tempDir, "videos/2.mp4")).
+			Return(io.EOF).Times(videoAttempts)
+		require.Error(t, l.downloadVideos(ctx, tempDir, book.Book{
+			Videos: []book.Address{{URL: "https://videos/2.mp4"}},
+		}))
+	})
+}
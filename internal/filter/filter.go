@@ -0,0 +1,260 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package filter implements the expressions selecting the books of the
+// catalog.
+//
+// An expression consists of terms combined by the operators "and", "or"
+// and "not", the terms written one after another are combined by "and",
+// the parentheses group the terms. The operators may also be written as
+// "&&", "||" and "!". The terms are:
+//
+//	id:42             the book with the identifier;
+//	id:120-180        the books with the identifiers in the range, the
+//	                  bounds may be omitted as in id:120- or id:-180,
+//	                  several ranges are separated by commas;
+//	title:джедай      the title contains the text;
+//	title~^Джедай     the title matches the regular expression;
+//	author:Дорофеев   the name of an author contains the text;
+//	author~regexp     the name of an author matches the regular expression;
+//	badge:new         the book has the badge;
+//	badge~regexp      a badge matches the regular expression;
+//	джедай            the title contains the text.
+//
+// The values containing spaces or parentheses are written in double
+// quotes. The texts are compared case-insensitively, the letters ё and е
+// are considered equal, the regular expressions are case-insensitive.
+//
+// For example: id:120-180 (author:Дорофеев or badge:new) not title~"^Как".
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/xorcare/miflib.go/internal/book"
+	"github.com/xorcare/miflib.go/internal/norm"
+)
+
+// Expr it's the parsed filter expression, the zero value matches all books.
+type Expr struct {
+	source string
+	root   node
+}
+
+// Parse parses the filter expression, the empty expression matches all
+// books.
+func Parse(s string) (Expr, error) {
+	p := parser{lexer: lexer{input: s}}
+	if err := p.next(); err != nil {
+		return Expr{}, err
+	}
+	if p.tok.kind == tokenEOF {
+		return Expr{source: s}, nil
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return Expr{}, err
+	}
+	if p.tok.kind != tokenEOF {
+		return Expr{}, p.unexpected()
+	}
+
+	return Expr{source: s, root: root}, nil
+}
+
+// String returns the source of the expression.
+func (e Expr) String() string {
+	return e.source
+}
+
+// Match reports whether the book matches the expression.
+func (e Expr) Match(bk book.Book) bool {
+	if e.root == nil {
+		return true
+	}
+	return e.root.match(bk)
+}
+
+// Books returns the books matching the expression keeping their order.
+func (e Expr) Books(bks []book.Book) []book.Book {
+	matched := make([]book.Book, 0, len(bks))
+	for _, bk := range bks {
+		if e.Match(bk) {
+			matched = append(matched, bk)
+		}
+	}
+	return matched
+}
+
+type node interface {
+	match(bk book.Book) bool
+}
+
+type and []node
+
+func (n and) match(bk book.Book) bool {
+	for _, operand := range n {
+		if !operand.match(bk) {
+			return false
+		}
+	}
+	return true
+}
+
+type or []node
+
+func (n or) match(bk book.Book) bool {
+	for _, operand := range n {
+		if operand.match(bk) {
+			return true
+		}
+	}
+	return false
+}
+
+type not struct {
+	node
+}
+
+func (n not) match(bk book.Book) bool {
+	return !n.node.match(bk)
+}
+
+// idRange it's the range of the identifiers, the zero bound is not limited.
+type idRange struct {
+	min, max int
+}
+
+type ids []idRange
+
+func (n ids) match(bk book.Book) bool {
+	for _, r := range n {
+		if (r.min == 0 || bk.ID >= r.min) && (r.max == 0 || bk.ID <= r.max) {
+			return true
+		}
+	}
+	return false
+}
+
+// text it's the matcher of the values of the field of the book.
+type text struct {
+	values func(bk book.Book) []string
+	// contains is the folded text which the value should contain, if
+	// exact is true, the value should be equal to it.
+	contains string
+	exact    bool
+	// re is the regular expression which the value should match.
+	re *regexp.Regexp
+}
+
+func (n text) match(bk book.Book) bool {
+	for _, value := range n.values(bk) {
+		switch {
+		case n.re != nil:
+			if n.re.MatchString(value) {
+				return true
+			}
+		case n.exact:
+			if norm.Fold(value) == n.contains {
+				return true
+			}
+		default:
+			if strings.Contains(norm.Fold(value), n.contains) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fields it's the text fields of the book available in the terms.
+var fields = map[string]func(bk book.Book) []string{
+	"title": func(bk book.Book) []string {
+		return []string{bk.Title.String()}
+	},
+	"author": func(bk book.Book) []string {
+		names := make([]string, 0, len(bk.Authors))
+		for _, author := range bk.Authors {
+			names = append(names, norm.String(author.Name))
+		}
+		return names
+	},
+	"badge": func(bk book.Book) []string {
+		badges := make([]string, 0, len(bk.Badges))
+		for _, badge := range bk.Badges {
+			badges = append(badges, norm.String(badge))
+		}
+		return badges
+	},
+}
+
+// newTerm creates the node of the term.
+func newTerm(field string, regex bool, value string) (node, error) {
+	if field == "id" {
+		if regex {
+			return nil, fmt.Errorf("filter: the field id doesn't support regular expressions")
+		}
+		return parseIDs(value)
+	}
+
+	values, ok := fields[field]
+	if !ok {
+		return nil, fmt.Errorf("filter: unknown field %q, expected one of: id, title, author, badge", field)
+	}
+
+	if regex {
+		re, err := regexp.Compile("(?i)" + value)
+		if err != nil {
+			return nil, fmt.Errorf("filter: invalid regular expression %q: %v", value, err)
+		}
+		return text{values: values, re: re}, nil
+	}
+
+	return text{values: values, contains: norm.Fold(value), exact: field == "badge"}, nil
+}
+
+// parseIDs parses the comma separated ranges of the identifiers.
+func parseIDs(value string) (ids, error) {
+	var ranges ids
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		bounds := strings.SplitN(field, "-", 2)
+
+		var r idRange
+		var err error
+		if r.min, err = parseID(bounds[0], len(bounds) == 2); err != nil {
+			return nil, fmt.Errorf("filter: invalid identifier range %q", field)
+		}
+		r.max = r.min
+		if len(bounds) == 2 {
+			if r.max, err = parseID(bounds[1], true); err != nil {
+				return nil, fmt.Errorf("filter: invalid identifier range %q", field)
+			}
+		}
+		if len(bounds) == 2 && bounds[0] == "" && bounds[1] == "" {
+			return nil, fmt.Errorf("filter: invalid identifier range %q", field)
+		}
+
+		ranges = append(ranges, r)
+	}
+
+	return ranges, nil
+}
+
+func parseID(s string, optional bool) (int, error) {
+	if s == "" && optional {
+		return 0, nil
+	}
+
+	id, err := strconv.Atoi(s)
+	if err == nil && id <= 0 {
+		err = fmt.Errorf("the identifier must be positive")
+	}
+
+	return id, err
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/xorcare/miflib.go/internal/book"
+)
+
+var books = []book.Book{
+	{
+		ID:      42,
+		Title:   "Джедайские техники",
+		Authors: []book.Author{{Name: "Максим Дорофеев"}},
+		Badges:  []string{"new"},
+	},
+	{
+		ID:      120,
+		Title:   "Ёлки-палки: как не сойти с ума",
+		Authors: []book.Author{{Name: "Иван Петров"}, {Name: "Анна Сидорова"}},
+	},
+	{
+		ID:      180,
+		Title:   "Путь джедая",
+		Authors: []book.Author{{Name: "Максим Дорофеев"}},
+		Badges:  []string{"bestseller", "New"},
+	},
+	{
+		ID:    200,
+		Title: "Clean Code",
+	},
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []int
+	}{
+		{expr: "", want: []int{42, 120, 180, 200}},
+		{expr: "id:120", want: []int{120}},
+		{expr: "id:120-180", want: []int{120, 180}},
+		{expr: "id:-120", want: []int{42, 120}},
+		{expr: "id:121-", want: []int{180, 200}},
+		{expr: "id:42,180-", want: []int{42, 180, 200}},
+		{expr: "ДЖЕДА", want: []int{42, 180}},
+		{expr: "title:елки", want: []int{120}},
+		{expr: `title:"как не"`, want: []int{120}},
+		{expr: "title~^путь", want: []int{180}},
+		{expr: `title~"^(clean|путь) "`, want: []int{180, 200}},
+		{expr: "author:дорофеев", want: []int{42, 180}},
+		{expr: "author~^анна", want: []int{120}},
+		{expr: "badge:new", want: []int{42, 180}},
+		{expr: "badge:ne", want: []int{}},
+		{expr: "badge~^best", want: []int{180}},
+		{expr: "author:дорофеев not badge:bestseller", want: []int{42}},
+		{expr: "author:дорофеев && !badge:bestseller", want: []int{42}},
+		{expr: "id:-100 or id:200-", want: []int{42, 200}},
+		{expr: "id:-100 || id:200- and clean", want: []int{42, 200}},
+		{expr: "(id:-100 or id:200-) and not clean", want: []int{42}},
+		{expr: "not (badge:new or author:петров)", want: []int{200}},
+		{expr: "NOT not id:42", want: []int{42}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := Parse(tt.expr)
+			require.NoError(t, err)
+			require.Equal(t, tt.expr, expr.String())
+
+			ids := []int{}
+			for _, bk := range expr.Books(books) {
+				ids = append(ids, bk.ID)
+			}
+			require.Equal(t, tt.want, ids)
+		})
+	}
+
+	t.Run("zero", func(t *testing.T) {
+		require.True(t, Expr{}.Match(books[0]))
+	})
+}
+
+func TestParse_error(t *testing.T) {
+	tests := map[string]string{
+		"year:2020":        `filter: unknown field "year" at position 0, expected one of: id, title, author, badge`,
+		"id:abc":           `filter: invalid identifier range "abc"`,
+		"id:0":             `filter: invalid identifier range "0"`,
+		"id:-":             `filter: invalid identifier range "-"`,
+		"id~1":             `filter: the field id doesn't support regular expressions`,
+		"title:":           `filter: empty value of the field "title" at position 0`,
+		`title~"("`:        "filter: invalid regular expression \"(\": error parsing regexp: missing closing ): `(?i)(`",
+		`title:"джедай`:    `filter: unterminated quoted string at position 6`,
+		"(id:1":            `filter: unexpected end of the expression "(id:1"`,
+		"id:1)":            `filter: unexpected ")" at position 4`,
+		"id:1 or":          `filter: unexpected end of the expression "id:1 or"`,
+		"id:1 & id:2":      `filter: unexpected "&" at position 5`,
+		"and id:1":         `filter: unexpected "and" at position 0`,
+		"not":              `filter: unexpected end of the expression "not"`,
+		"badge:new ((()))": `filter: unexpected ")" at position 13`,
+	}
+	for expr, want := range tests {
+		t.Run(expr, func(t *testing.T) {
+			_, err := Parse(expr)
+			require.EqualError(t, err, want)
+		})
+	}
+}
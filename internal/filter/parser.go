@@ -0,0 +1,300 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenLeft
+	tokenRight
+	tokenAnd
+	tokenOr
+	tokenNot
+	// tokenTerm it's the term with the field, for example title:джедай.
+	tokenTerm
+	// tokenText it's the value without the field.
+	tokenText
+)
+
+type token struct {
+	kind tokenKind
+	pos  int
+	// field, regex and value are set for the terms.
+	field string
+	regex bool
+	value string
+	raw   string
+}
+
+// lexer splits the expression into the tokens.
+type lexer struct {
+	input string
+	pos   int
+}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.input) {
+		r, size := utf8.DecodeRuneInString(l.input[l.pos:])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		l.pos += size
+	}
+
+	start := l.pos
+	if l.pos >= len(l.input) {
+		return token{kind: tokenEOF, pos: start}, nil
+	}
+
+	rest := l.input[l.pos:]
+	for _, op := range []struct {
+		text string
+		kind tokenKind
+	}{
+		{text: "(", kind: tokenLeft},
+		{text: ")", kind: tokenRight},
+		{text: "&&", kind: tokenAnd},
+		{text: "||", kind: tokenOr},
+		{text: "!", kind: tokenNot},
+	} {
+		if strings.HasPrefix(rest, op.text) {
+			l.pos += len(op.text)
+			return token{kind: op.kind, pos: start, raw: op.text}, nil
+		}
+	}
+
+	if rest[0] == '"' {
+		value, err := l.quoted()
+		if err != nil {
+			return token{}, err
+		}
+		return token{kind: tokenText, pos: start, value: value, raw: l.input[start:l.pos]}, nil
+	}
+
+	word := l.word()
+	switch strings.ToLower(word) {
+	case "and":
+		return token{kind: tokenAnd, pos: start, raw: word}, nil
+	case "or":
+		return token{kind: tokenOr, pos: start, raw: word}, nil
+	case "not":
+		return token{kind: tokenNot, pos: start, raw: word}, nil
+	}
+
+	tok := token{kind: tokenText, pos: start, value: word, raw: word}
+	if word != "" && l.pos < len(l.input) && (l.input[l.pos] == ':' || l.input[l.pos] == '~') {
+		if !isField(word) {
+			return token{}, fmt.Errorf("filter: unknown field %q at position %d,"+
+				" expected one of: id, title, author, badge", word, start)
+		}
+		tok.kind, tok.field, tok.regex = tokenTerm, strings.ToLower(word), l.input[l.pos] == '~'
+		l.pos++
+		if l.pos < len(l.input) && l.input[l.pos] == '"' {
+			value, err := l.quoted()
+			if err != nil {
+				return token{}, err
+			}
+			tok.value = value
+		} else {
+			tok.value = l.value()
+		}
+		tok.raw = l.input[start:l.pos]
+		if tok.value == "" {
+			return token{}, fmt.Errorf("filter: empty value of the field %q at position %d", tok.field, start)
+		}
+	} else if tok.value == "" {
+		return token{}, fmt.Errorf("filter: unexpected %q at position %d", l.input[l.pos:l.pos+1], start)
+	}
+
+	return tok, nil
+}
+
+// word reads the text up to a space or a character of the operators.
+func (l *lexer) word() string {
+	start := l.pos
+	for l.pos < len(l.input) {
+		r, size := utf8.DecodeRuneInString(l.input[l.pos:])
+		if unicode.IsSpace(r) || strings.ContainsRune(`()"!&|:~`, r) {
+			break
+		}
+		l.pos += size
+	}
+	return l.input[start:l.pos]
+}
+
+// value reads the unquoted value of the term up to a space or parenthesis.
+func (l *lexer) value() string {
+	start := l.pos
+	for l.pos < len(l.input) {
+		r, size := utf8.DecodeRuneInString(l.input[l.pos:])
+		if unicode.IsSpace(r) || r == '(' || r == ')' {
+			break
+		}
+		l.pos += size
+	}
+	return l.input[start:l.pos]
+}
+
+// quoted reads the value in double quotes, the quote and the backslash
+// inside it are escaped by the backslash.
+func (l *lexer) quoted() (string, error) {
+	start := l.pos
+	l.pos++
+
+	buf := strings.Builder{}
+	for l.pos < len(l.input) {
+		c := l.input[l.pos]
+		switch {
+		case c == '"':
+			l.pos++
+			return buf.String(), nil
+		case c == '\\' && l.pos+1 < len(l.input) && strings.IndexByte(`"\`, l.input[l.pos+1]) >= 0:
+			buf.WriteByte(l.input[l.pos+1])
+			l.pos += 2
+		default:
+			buf.WriteByte(c)
+			l.pos++
+		}
+	}
+
+	return "", fmt.Errorf("filter: unterminated quoted string at position %d", start)
+}
+
+func isField(word string) bool {
+	word = strings.ToLower(word)
+	_, ok := fields[word]
+	return ok || word == "id"
+}
+
+// parser builds the tree of the expression by the recursive descent.
+type parser struct {
+	lexer lexer
+	tok   token
+}
+
+func (p *parser) next() (err error) {
+	p.tok, err = p.lexer.next()
+	return err
+}
+
+func (p *parser) unexpected() error {
+	if p.tok.kind == tokenEOF {
+		return fmt.Errorf("filter: unexpected end of the expression %q", p.lexer.input)
+	}
+	return fmt.Errorf("filter: unexpected %q at position %d", p.tok.raw, p.tok.pos)
+}
+
+// parseOr parses: and { "or" and }.
+func (p *parser) parseOr() (node, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	operands := or{first}
+	for p.tok.kind == tokenOr {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		operand, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, operand)
+	}
+
+	if len(operands) == 1 {
+		return first, nil
+	}
+	return operands, nil
+}
+
+// parseAnd parses: not { ["and"] not }.
+func (p *parser) parseAnd() (node, error) {
+	first, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	operands := and{first}
+	for {
+		switch p.tok.kind {
+		case tokenAnd:
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+		case tokenNot, tokenLeft, tokenTerm, tokenText:
+		default:
+			if len(operands) == 1 {
+				return first, nil
+			}
+			return operands, nil
+		}
+
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, operand)
+	}
+}
+
+// parseNot parses: "not" not | primary.
+func (p *parser) parseNot() (node, error) {
+	if p.tok.kind != tokenNot {
+		return p.parsePrimary()
+	}
+
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	operand, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	return not{operand}, nil
+}
+
+// parsePrimary parses: "(" or ")" | term.
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokenLeft:
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		operand, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokenRight {
+			return nil, p.unexpected()
+		}
+		return operand, p.next()
+	case tokenTerm:
+		operand, err := newTerm(tok.field, tok.regex, tok.value)
+		if err != nil {
+			return nil, err
+		}
+		return operand, p.next()
+	case tokenText:
+		operand, err := newTerm("title", false, tok.value)
+		if err != nil {
+			return nil, err
+		}
+		return operand, p.next()
+	default:
+		return nil, p.unexpected()
+	}
+}
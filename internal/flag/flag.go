@@ -6,7 +6,10 @@ import (
 
 	"github.com/urfave/cli/v2"
 
+	"github.com/xorcare/miflib.go/internal/config"
+	"github.com/xorcare/miflib.go/internal/downloader"
 	"github.com/xorcare/miflib.go/internal/flags"
+	"github.com/xorcare/miflib.go/internal/logging"
 )
 
 func init() {
@@ -22,29 +25,27 @@ func init() {
 
 // Username is a instance of cli flag.
 var Username = &cli.StringFlag{
-	Name:     flags.Username,
-	Aliases:  []string{"u"},
-	Usage:    "username for the library",
-	Required: true,
-	EnvVars:  flags.Env(flags.Username),
+	Name:    flags.Username,
+	Aliases: []string{"u"},
+	Usage:   "username for the library",
+	EnvVars: flags.Env(flags.Username),
 }
 
 // Password is a instance of cli flag.
 var Password = &cli.StringFlag{
-	Name:     flags.Password,
-	Aliases:  []string{"p"},
-	Usage:    "password for the library",
-	Required: true,
-	EnvVars:  flags.Env(flags.Password),
+	Name:    flags.Password,
+	Aliases: []string{"p"},
+	Usage: "password for the library, the --" + flags.PasswordFile + ", --" + flags.PasswordStdin +
+		" and --" + flags.PasswordCommand + " don't leak it into the shell history and the process list",
+	EnvVars: flags.Env(flags.Password),
 }
 
 // Hostname is a instance of cli flag.
 var Hostname = &cli.StringFlag{
-	Name:     flags.Hostname,
-	Aliases:  []string{"h"},
-	Usage:    "hostname for the library",
-	Required: true,
-	EnvVars:  flags.Env(flags.Hostname),
+	Name:    flags.Hostname,
+	Aliases: []string{"h"},
+	Usage:   "hostname for the library",
+	EnvVars: flags.Env(flags.Hostname),
 }
 
 // Directory is a instance of cli flag.
@@ -91,3 +92,282 @@ var Verbose = &cli.BoolFlag{
 	EnvVars: flags.Env(flags.Verbose),
 	Value:   false,
 }
+
+// DirTemplate is a instance of cli flag.
+var DirTemplate = &cli.StringFlag{
+	Name: flags.DirTemplate,
+	Usage: "text/template of the book directory relative to the library directory," +
+		" slashes separate nested directories. The fields .ID, .Title, .Subtitle," +
+		" .Author, .Authors, .Badge, .Badges and the functions join, pad are available.",
+	EnvVars: flags.Env(flags.DirTemplate),
+	Value:   downloader.DefaultDirTemplate,
+}
+
+// FileTemplate is a instance of cli flag.
+var FileTemplate = &cli.StringFlag{
+	Name: flags.FileTemplate,
+	Usage: "text/template of the ebook, audiobook and demo files relative to the book directory," +
+		" slashes separate nested directories. The fields .Category, .Format, .Index, .Part, .Parts," +
+		" .Title, .Author, .Book and the functions join, pad are available.",
+	EnvVars: flags.Env(flags.FileTemplate),
+	Value:   downloader.DefaultFileTemplate,
+}
+
+// FromDirTemplate is a instance of cli flag.
+var FromDirTemplate = &cli.StringFlag{
+	Name:    flags.FromDirTemplate,
+	Usage:   "the previous template of the book directory, see --" + flags.DirTemplate,
+	EnvVars: flags.Env(flags.FromDirTemplate),
+	Value:   downloader.DefaultDirTemplate,
+}
+
+// FromFileTemplate is a instance of cli flag.
+var FromFileTemplate = &cli.StringFlag{
+	Name:    flags.FromFileTemplate,
+	Usage:   "the previous template of the book files, see --" + flags.FileTemplate,
+	EnvVars: flags.Env(flags.FromFileTemplate),
+	Value:   downloader.DefaultFileTemplate,
+}
+
+// Filesystem is a instance of cli flag.
+var Filesystem = &cli.StringFlag{
+	Name: flags.Filesystem,
+	Usage: "profile of the file system for which the file names are cleared:" +
+		" posix, macos, windows, onedrive or portable which is valid on all of them",
+	EnvVars: flags.Env(flags.Filesystem),
+	Value:   downloader.ProfilePortable,
+}
+
+// FromFilesystem is a instance of cli flag.
+var FromFilesystem = &cli.StringFlag{
+	Name:    flags.FromFilesystem,
+	Usage:   "the previous profile of the file system, see --" + flags.Filesystem,
+	EnvVars: flags.Env(flags.FromFilesystem),
+	Value:   downloader.ProfilePortable,
+}
+
+// DryRun is a instance of cli flag.
+var DryRun = &cli.BoolFlag{
+	Name:    flags.DryRun,
+	Usage:   "only print what would be done without changing anything",
+	EnvVars: flags.Env(flags.DryRun),
+}
+
+// Translit is a instance of cli flag.
+var Translit = &cli.StringFlag{
+	Name: flags.Translit,
+	Usage: "transliterate the Cyrillic letters of the file names by the scheme:" +
+		" gost (GOST 7.79-2000 system B), iso9 (ISO 9:1995 without diacritics) or simple," +
+		" the transliterated names are ASCII, the names are not transliterated by default",
+	EnvVars: flags.Env(flags.Translit),
+}
+
+// FromTranslit is a instance of cli flag.
+var FromTranslit = &cli.StringFlag{
+	Name:    flags.FromTranslit,
+	Usage:   "the previous transliteration scheme, see --" + flags.Translit,
+	EnvVars: flags.Env(flags.FromTranslit),
+}
+
+// Include is a instance of cli flag.
+var Include = &cli.StringFlag{
+	Name: flags.Include,
+	Usage: "comma separated categories of the materials to download: ebook, audiobook, cover," +
+		" demo, photos, videos, author-photos or all",
+	EnvVars: flags.Env(flags.Include),
+	Value: string(downloader.CategoryEbook) + "," + string(downloader.CategoryAudiobook) +
+		"," + string(downloader.CategoryCover),
+}
+
+// EbookFormats is a instance of cli flag.
+var EbookFormats = &cli.StringFlag{
+	Name: flags.EbookFormats,
+	Usage: "rule of the ebook formats to download, a comma separated list of the formats, * for all," +
+		" -format to exclude and a>b+c to download only the first available alternative," +
+		" for example: epub,pdf or *,-fb2",
+	EnvVars: flags.Env(flags.EbookFormats),
+	Value:   downloader.DefaultFormatRules[downloader.CategoryEbook],
+}
+
+// AudiobookFormats is a instance of cli flag.
+var AudiobookFormats = &cli.StringFlag{
+	Name:    flags.AudiobookFormats,
+	Usage:   "rule of the audiobook formats to download, for example: m4b>zip>mp3, see --" + flags.EbookFormats,
+	EnvVars: flags.Env(flags.AudiobookFormats),
+	Value:   downloader.DefaultFormatRules[downloader.CategoryAudiobook],
+}
+
+// DemoFormats is a instance of cli flag.
+var DemoFormats = &cli.StringFlag{
+	Name:    flags.DemoFormats,
+	Usage:   "rule of the demo formats to download, see --" + flags.EbookFormats,
+	EnvVars: flags.Env(flags.DemoFormats),
+	Value:   downloader.DefaultFormatRules[downloader.CategoryDemo],
+}
+
+// Filter is a instance of cli flag.
+var Filter = &cli.StringFlag{
+	Name: flags.Filter,
+	Usage: "expression selecting the books to download, the terms id:120-180, title:text, title~regexp," +
+		" author:text, author~regexp, badge:new are combined by and, or, not and parentheses," +
+		` for example: 'id:120- (author:Дорофеев or badge:new)'`,
+	EnvVars: flags.Env(flags.Filter),
+}
+
+// ListFile is a instance of cli flag.
+var ListFile = &cli.StringFlag{
+	Name: flags.ListFile,
+	Usage: "file with the list of the books to download, one book id or title per line" +
+		` or a JSON array such as [42, {"title": "..."}]`,
+	EnvVars:   flags.Env(flags.ListFile),
+	TakesFile: true,
+}
+
+// Prune is a instance of cli flag.
+var Prune = &cli.BoolFlag{
+	Name:    flags.Prune,
+	Usage:   "remove from the directory the books which are not in the --" + flags.ListFile,
+	EnvVars: flags.Env(flags.Prune),
+}
+
+// MinFreeSpace is a instance of cli flag.
+var MinFreeSpace = &cli.StringFlag{
+	Name: flags.MinFreeSpace,
+	Usage: "free space of the directory which should be kept, the downloading is paused" +
+		" while the free space left after the download of the next file is below it, for example: 500MiB or 2G, 0 disables the pauses",
+	EnvVars: flags.Env(flags.MinFreeSpace),
+	Value:   "0",
+}
+
+// SpaceCheck is a instance of cli flag.
+var SpaceCheck = &cli.StringFlag{
+	Name: flags.SpaceCheck,
+	Usage: "action if the estimated size of the download and the --" + flags.MinFreeSpace +
+		" exceed the free space of the directory before the start: fail, warn or off, the check estimates" +
+		" the size of the selected books before the download",
+	EnvVars: flags.Env(flags.SpaceCheck),
+	Value:   "off",
+}
+
+// Quota is a instance of cli flag.
+var Quota = &cli.StringFlag{
+	Name: flags.Quota,
+	Usage: "maximum size of the files downloaded by the run, for example: 20GiB, the books" +
+		" which don't fit are left for the next run, the size is not limited by default",
+	EnvVars: flags.Env(flags.Quota),
+}
+
+// Config is a instance of cli flag.
+var Config = &cli.StringFlag{
+	Name: flags.Config,
+	Usage: "configuration file with the profiles of the flags, by default " + config.FileName +
+		" in the configuration directory of the user is used if it exists",
+	EnvVars:   flags.Env(flags.Config),
+	TakesFile: true,
+}
+
+// Profile is a instance of cli flag.
+var Profile = &cli.StringFlag{
+	Name:    flags.Profile,
+	Usage:   "profile of the --" + flags.Config + " file, the default profile of the file is used if not set",
+	EnvVars: flags.Env(flags.Profile),
+}
+
+// PasswordFile is a instance of cli flag.
+var PasswordFile = &cli.StringFlag{
+	Name:      flags.PasswordFile,
+	Usage:     "file with the password for the library in the first line",
+	EnvVars:   flags.Env(flags.PasswordFile),
+	TakesFile: true,
+}
+
+// PasswordStdin is a instance of cli flag.
+var PasswordStdin = &cli.BoolFlag{
+	Name:    flags.PasswordStdin,
+	Usage:   "read the password for the library from the first line of the standard input",
+	EnvVars: flags.Env(flags.PasswordStdin),
+}
+
+// PasswordCommand is a instance of cli flag.
+var PasswordCommand = &cli.StringFlag{
+	Name: flags.PasswordCommand,
+	Usage: "shell command printing the password for the library, for example: 'pass show miflib'," +
+		" without a password source the .netrc entry of the --" + flags.Hostname +
+		" is used or the password is prompted in the terminal",
+	EnvVars: flags.Env(flags.PasswordCommand),
+}
+
+// LogFormat is a instance of cli flag.
+var LogFormat = &cli.StringFlag{
+	Name:    flags.LogFormat,
+	Usage:   "format of the logs: console or json",
+	EnvVars: flags.Env(flags.LogFormat),
+	Value:   logging.FormatConsole,
+}
+
+// LogFile is a instance of cli flag.
+var LogFile = &cli.StringFlag{
+	Name:      flags.LogFile,
+	Usage:     "file of the logs, the logs are written to the standard error by default",
+	EnvVars:   flags.Env(flags.LogFile),
+	TakesFile: true,
+}
+
+// LogMaxSize is a instance of cli flag.
+var LogMaxSize = &cli.StringFlag{
+	Name:    flags.LogMaxSize,
+	Usage:   "size of the --" + flags.LogFile + " after which it is rotated, 0 disables the rotation",
+	EnvVars: flags.Env(flags.LogMaxSize),
+	Value:   "100MiB",
+}
+
+// LogMaxBackups is a instance of cli flag.
+var LogMaxBackups = &cli.IntFlag{
+	Name:    flags.LogMaxBackups,
+	Usage:   "number of the rotated log files to keep",
+	EnvVars: flags.Env(flags.LogMaxBackups),
+	Value:   5,
+}
+
+// LogLevel is a instance of cli flag.
+var LogLevel = &cli.StringFlag{
+	Name: flags.LogLevel,
+	Usage: "comma separated levels of the logs: debug, info, warn or error, the level is set for the" +
+		" subsystems api, downloader and cli as subsystem=level, for example: warn,downloader=info," +
+		" the --" + flags.Verbose + " sets the debug level",
+	EnvVars: flags.Env(flags.LogLevel),
+	Value:   "info",
+}
+
+// LogSampling is a instance of cli flag.
+var LogSampling = &cli.BoolFlag{
+	Name: flags.LogSampling,
+	Usage: "drop the repeated messages under load, the first 100 messages per second are kept," +
+		" use --" + flags.LogSampling + "=false to keep all messages",
+	EnvVars: flags.Env(flags.LogSampling),
+	Value:   true,
+}
+
+// Output is a instance of cli flag.
+var Output = &cli.StringFlag{
+	Name:    flags.Output,
+	Usage:   "format of the output: table, json (one object per line) or csv",
+	EnvVars: flags.Env(flags.Output),
+	Value:   "table",
+}
+
+// Sort is a instance of cli flag.
+var Sort = &cli.StringFlag{
+	Name: flags.Sort,
+	Usage: "order of the books: id, title, author or size, the leading minus reverses the order," +
+		" for example: -size",
+	EnvVars: flags.Env(flags.Sort),
+	Value:   "id",
+}
+
+// Repair is a instance of cli flag.
+var Repair = &cli.BoolFlag{
+	Name:    flags.Repair,
+	Usage:   "download again the damaged and the missing files, the credentials are required",
+	EnvVars: flags.Env(flags.Repair),
+}
@@ -18,6 +18,39 @@ const (
 	HTTPResponseHeaderTimeout = "http-response-header-timeout"
 	HTTPTimeout               = "http-timeout"
 	Verbose                   = "verbose"
+	DirTemplate               = "dir-template"
+	FileTemplate              = "file-template"
+	FromDirTemplate           = "from-dir-template"
+	FromFileTemplate          = "from-file-template"
+	Filesystem                = "filesystem"
+	FromFilesystem            = "from-filesystem"
+	DryRun                    = "dry-run"
+	Translit                  = "translit"
+	FromTranslit              = "from-translit"
+	Include                   = "include"
+	EbookFormats              = "ebook-formats"
+	AudiobookFormats          = "audiobook-formats"
+	DemoFormats               = "demo-formats"
+	Filter                    = "filter"
+	ListFile                  = "list-file"
+	Prune                     = "prune"
+	MinFreeSpace              = "min-free-space"
+	SpaceCheck                = "space-check"
+	Quota                     = "quota"
+	Config                    = "config"
+	Profile                   = "profile"
+	PasswordFile              = "password-file"
+	PasswordStdin             = "password-stdin"
+	PasswordCommand           = "password-command"
+	LogFormat                 = "log-format"
+	LogFile                   = "log-file"
+	LogMaxSize                = "log-max-size"
+	LogMaxBackups             = "log-max-backups"
+	LogLevel                  = "log-level"
+	LogSampling               = "log-sampling"
+	Output                    = "output"
+	Sort                      = "sort"
+	Repair                    = "repair"
 )
 
 // Env it's a function for conversion flag name to env variable name.
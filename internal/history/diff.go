@@ -0,0 +1,100 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Change it's a change of a single value of the book metadata.
+type Change struct {
+	// Path to the value, for example files.ebook.epub[0].url.
+	Path string
+	// Old is the previous value, nil if the value has been added.
+	Old interface{}
+	// New is the current value, nil if the value has been removed.
+	New interface{}
+}
+
+// String implements the fmt.Stringer interface.
+func (c Change) String() string {
+	switch {
+	case c.Old == nil:
+		return fmt.Sprintf("+ %s: %s", c.Path, value(c.New))
+	case c.New == nil:
+		return fmt.Sprintf("- %s: %s", c.Path, value(c.Old))
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", c.Path, value(c.Old), value(c.New))
+	}
+}
+
+// Diff compares two versions of the book metadata encoded in JSON and
+// returns the changes sorted by path.
+func Diff(old, new []byte) ([]Change, error) {
+	var o, n interface{}
+	if err := json.Unmarshal(old, &o); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(new, &n); err != nil {
+		return nil, err
+	}
+
+	ov, nv := map[string]interface{}{}, map[string]interface{}{}
+	flatten("", o, ov)
+	flatten("", n, nv)
+
+	var changes []Change
+	for key, val := range ov {
+		if cur, ok := nv[key]; !ok {
+			changes = append(changes, Change{Path: key, Old: val})
+		} else if cur != val {
+			changes = append(changes, Change{Path: key, Old: val, New: cur})
+		}
+	}
+	for key, val := range nv {
+		if _, ok := ov[key]; !ok {
+			changes = append(changes, Change{Path: key, New: val})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	return changes, nil
+}
+
+// flatten converts a decoded JSON document into a set of paths to
+// scalar values.
+func flatten(prefix string, v interface{}, dst map[string]interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, val := range v {
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			flatten(key, val, dst)
+		}
+	case []interface{}:
+		for i, val := range v {
+			flatten(prefix+"["+strconv.Itoa(i)+"]", val, dst)
+		}
+	case nil:
+		// null values are equivalent to missing values.
+	default:
+		dst[prefix] = v
+	}
+}
+
+func value(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package history
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xorcare/miflib.go/internal/book"
+	"github.com/xorcare/miflib.go/internal/library"
+)
+
+// Dir it's the name of the directory inside the book directory where
+// the previous versions of the book metadata are stored.
+const Dir = ".history"
+
+// timeLayout it's the layout of the snapshot names, the nanoseconds keep
+// apart the versions changed within the same second.
+const timeLayout = "20060102T150405.000000000Z"
+
+// parseLayout it's the layout by which the snapshot names are parsed, it
+// also accepts the names without the fractional seconds given by the
+// earlier versions.
+const parseLayout = "20060102T150405Z"
+
+// Version it's a single version of the book metadata.
+type Version struct {
+	// Time when the version was recorded.
+	Time time.Time
+	// Path to the file with the version of the metadata.
+	Path string
+}
+
+// Record writes the book metadata to the book directory. If the directory
+// already contains the metadata that differs from the new one, the previous
+// version is moved to the history. It reports whether the metadata has been
+// changed.
+func Record(bookpath string, bk book.Book) (changed bool, err error) {
+	data, err := json.MarshalIndent(bk, "", "\t")
+	if err != nil {
+		return false, err
+	}
+	data = append(data, '\n')
+
+	filename := filepath.Join(bookpath, library.BookFile)
+	old, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return true, ioutil.WriteFile(filename, data, 0644)
+	}
+	if err != nil {
+		return false, err
+	}
+	if bytes.Equal(old, data) {
+		return false, nil
+	}
+
+	if err := snapshot(bookpath, old); err != nil {
+		return false, err
+	}
+
+	return true, ioutil.WriteFile(filename, data, 0644)
+}
+
+// snapshot saves the previous version of the metadata keeping the time when
+// it was recorded.
+func snapshot(bookpath string, data []byte) error {
+	info, err := os.Stat(filepath.Join(bookpath, library.BookFile))
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(bookpath, Dir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	modTime := info.ModTime().UTC()
+	filename := filepath.Join(dir, modTime.Format(timeLayout)+".json")
+	for {
+		saved, err := ioutil.ReadFile(filename)
+		if os.IsNotExist(err) {
+			break
+		} else if err != nil {
+			return err
+		}
+		if bytes.Equal(saved, data) {
+			// the version has already been saved by the interrupted run.
+			return nil
+		}
+		// the file systems with the coarse modification time give the same
+		// time to the versions changed one after another.
+		modTime = modTime.Add(time.Nanosecond)
+		filename = filepath.Join(dir, modTime.Format(timeLayout)+".json")
+	}
+
+	if err := ioutil.WriteFile(filename, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Chtimes(filename, modTime, modTime)
+}
+
+// Versions returns all known versions of the book metadata in chronological
+// order, the last one is the current version.
+func Versions(bookpath string) ([]Version, error) {
+	var versions []Version
+
+	infos, err := ioutil.ReadDir(filepath.Join(bookpath, Dir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, info := range infos {
+		if info.IsDir() || filepath.Ext(info.Name()) != ".json" {
+			continue
+		}
+		tm, err := time.Parse(parseLayout, strings.TrimSuffix(info.Name(), ".json"))
+		if err != nil {
+			continue
+		}
+		versions = append(versions, Version{
+			Time: tm,
+			Path: filepath.Join(bookpath, Dir, info.Name()),
+		})
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Time.Before(versions[j].Time)
+	})
+
+	filename := filepath.Join(bookpath, library.BookFile)
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(versions, Version{
+		Time: info.ModTime().UTC(),
+		Path: filename,
+	}), nil
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package history
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/xorcare/miflib.go/internal/book"
+	"github.com/xorcare/miflib.go/internal/library"
+)
+
+func TestRecord(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	bk := book.Book{ID: 42, Title: "Джедайские техники", Description: "old"}
+
+	changed, err := Record(tempDir, bk)
+	require.NoError(t, err)
+	require.True(t, changed)
+
+	changed, err = Record(tempDir, bk)
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	// moves the current version to the past so that the snapshot
+	// gets a distinguishable time.
+	past := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, os.Chtimes(filepath.Join(tempDir, library.BookFile), past, past))
+
+	bk.Description = "new"
+	changed, err = Record(tempDir, bk)
+	require.NoError(t, err)
+	require.True(t, changed)
+
+	versions, err := Versions(tempDir)
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	require.Equal(t, past, versions[0].Time)
+	require.Equal(t, filepath.Join(tempDir, Dir, "20200501T100000.000000000Z.json"), versions[0].Path)
+	require.Equal(t, filepath.Join(tempDir, library.BookFile), versions[1].Path)
+
+	old, err := ioutil.ReadFile(versions[0].Path)
+	require.NoError(t, err)
+	cur, err := ioutil.ReadFile(versions[1].Path)
+	require.NoError(t, err)
+
+	changes, err := Diff(old, cur)
+	require.NoError(t, err)
+	require.Equal(t, []Change{{Path: "description", Old: "old", New: "new"}}, changes)
+
+	// the changes within the same second don't overwrite each other.
+	require.NoError(t, os.Chtimes(filepath.Join(tempDir, library.BookFile), past, past))
+	bk.Description = "newest"
+	changed, err = Record(tempDir, bk)
+	require.NoError(t, err)
+	require.True(t, changed)
+
+	// the snapshot named by the earlier versions is still read.
+	legacy := filepath.Join(tempDir, Dir, "20200101T000000Z.json")
+	require.NoError(t, ioutil.WriteFile(legacy, old, 0644))
+
+	versions, err = Versions(tempDir)
+	require.NoError(t, err)
+	require.Len(t, versions, 4)
+	require.Equal(t, legacy, versions[0].Path)
+	require.Equal(t, filepath.Join(tempDir, Dir, "20200501T100000.000000000Z.json"), versions[1].Path)
+	require.Equal(t, filepath.Join(tempDir, Dir, "20200501T100000.000000001Z.json"), versions[2].Path)
+
+	data, err := ioutil.ReadFile(versions[2].Path)
+	require.NoError(t, err)
+	require.Equal(t, cur, data)
+}
+
+func TestDiff(t *testing.T) {
+	changes, err := Diff(
+		[]byte(`{"id":1,"files":{"ebook":{"epub":[{"url":"https://epub"}]}},"videos":[{"url":"v"}]}`),
+		[]byte(`{"id":1,"files":{"ebook":{"pdf":[{"url":"https://pdf"}]}},"videos":null}`),
+	)
+	require.NoError(t, err)
+	require.Equal(t, []Change{
+		{Path: "files.ebook.epub[0].url", Old: "https://epub"},
+		{Path: "files.ebook.pdf[0].url", New: "https://pdf"},
+		{Path: "videos[0].url", Old: "v"},
+	}, changes)
+
+	require.Equal(t, `- videos[0].url: "v"`, changes[2].String())
+	require.Equal(t, `+ files.ebook.pdf[0].url: "https://pdf"`, changes[1].String())
+	require.Equal(t, `~ title: "a" -> "b"`, Change{Path: "title", Old: "a", New: "b"}.String())
+}
@@ -0,0 +1,261 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package ignore implements the ignore file of the library which lists the
+// books and the materials that should never be downloaded.
+//
+// The ignore file is similar to .gitignore, every line is a rule, empty
+// lines and lines starting with # are skipped, the rules starting with !
+// negate the previous rules, the last matching rule wins. A rule consists
+// of the whitespace separated terms which all should match:
+//
+//	id:42 or 42                the book with the identifier;
+//	id:120-180 or 120-180      the books with the identifiers in the range;
+//	title:"Большая коллекция*" the books with the title matching the
+//	                           pattern, * matches any text, ? matches
+//	                           any character;
+//	category:audiobook         the category of the materials;
+//	format:mp3                 the format of the files.
+//
+// The line without terms is the title pattern, for example:
+//
+//	# huge audio collections
+//	Аудиоколлекция*
+//	!Аудиоколлекция: избранное
+//	format:fb2
+//	title:"Путь *" category:audiobook
+//
+// The rules without the category and format ignore the whole book, the
+// other rules ignore only the matching materials. The titles are compared
+// case-insensitively, the letters ё and е are considered equal.
+package ignore
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/xorcare/miflib.go/internal/book"
+	"github.com/xorcare/miflib.go/internal/norm"
+)
+
+// Rules it's the rules of the ignore file, the zero value ignores nothing.
+type Rules struct {
+	rules []rule
+}
+
+type rule struct {
+	source   string
+	negate   bool
+	min, max int
+	title    *regexp.Regexp
+	category string
+	format   string
+}
+
+// Read reads the rules from the file, the missing file contains no rules.
+func Read(filename string) (Rules, error) {
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return Rules{}, nil
+	} else if err != nil {
+		return Rules{}, err
+	}
+
+	rules, err := Parse(data)
+	if err != nil {
+		return Rules{}, fmt.Errorf("ignore: %s: %v", filename, err)
+	}
+
+	return rules, nil
+}
+
+// Parse parses the rules of the ignore file.
+func Parse(data []byte) (Rules, error) {
+	var rules Rules
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		r, err := parseRule(text)
+		if err != nil {
+			return Rules{}, fmt.Errorf("line %d: %v", line, err)
+		}
+		rules.rules = append(rules.rules, r)
+	}
+
+	return rules, scanner.Err()
+}
+
+func parseRule(text string) (rule, error) {
+	r := rule{source: text}
+	if strings.HasPrefix(text, "!") {
+		r.negate = true
+		text = strings.TrimSpace(text[1:])
+	}
+
+	terms, err := split(text)
+	if err != nil {
+		return rule{}, err
+	}
+	if len(terms) == 0 {
+		return rule{}, fmt.Errorf("empty rule %q", r.source)
+	}
+
+	if !isTerm(terms[0]) {
+		// the whole line is the title pattern.
+		r.title = pattern(text)
+		return r, nil
+	}
+
+	for _, term := range terms {
+		key, value := "id", term
+		if i := strings.IndexByte(term, ':'); i >= 0 {
+			key, value = term[:i], term[i+1:]
+		}
+		if value == "" {
+			return rule{}, fmt.Errorf("empty value of the term %q", term)
+		}
+
+		switch key {
+		case "id":
+			if r.min, r.max, err = parseRange(value); err != nil {
+				return rule{}, err
+			}
+		case "title":
+			r.title = pattern(value)
+		case "category":
+			r.category = strings.ToLower(value)
+		case "format":
+			r.format = strings.ToLower(value)
+		default:
+			return rule{}, fmt.Errorf("unknown term %q, expected one of: id, title, category, format", key)
+		}
+	}
+
+	return r, nil
+}
+
+// isTerm reports whether the word is a term of the rule.
+func isTerm(word string) bool {
+	for _, key := range []string{"id:", "title:", "category:", "format:"} {
+		if strings.HasPrefix(word, key) {
+			return true
+		}
+	}
+	return idPattern.MatchString(word)
+}
+
+// idPattern it's the pattern of the identifier or the range of identifiers.
+var idPattern = regexp.MustCompile(`^\d+(-\d+)?$`)
+
+// split splits the text by the whitespaces, the text in double quotes is
+// not split, the quotes are removed.
+func split(text string) ([]string, error) {
+	var terms []string
+	buf := strings.Builder{}
+	quoted, started := false, false
+	for _, r := range text {
+		switch {
+		case r == '"':
+			quoted, started = !quoted, true
+		case !quoted && (r == ' ' || r == '\t'):
+			if started {
+				terms = append(terms, buf.String())
+				buf.Reset()
+				started = false
+			}
+		default:
+			buf.WriteRune(r)
+			started = true
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quoted string in %q", text)
+	}
+	if started {
+		terms = append(terms, buf.String())
+	}
+
+	return terms, nil
+}
+
+// parseRange parses the identifier or the range of identifiers.
+func parseRange(s string) (min, max int, err error) {
+	bounds := strings.SplitN(s, "-", 2)
+	if min, err = strconv.Atoi(bounds[0]); err != nil || min <= 0 {
+		return 0, 0, fmt.Errorf("invalid book id %q", s)
+	}
+	max = min
+	if len(bounds) == 2 {
+		if max, err = strconv.Atoi(bounds[1]); err != nil || max < min {
+			return 0, 0, fmt.Errorf("invalid range of book ids %q", s)
+		}
+	}
+	return min, max, nil
+}
+
+// pattern compiles the title pattern to the regular expression.
+func pattern(s string) *regexp.Regexp {
+	expr := strings.Builder{}
+	expr.WriteString("^")
+	for _, r := range norm.Fold(s) {
+		switch r {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+
+	return regexp.MustCompile(expr.String())
+}
+
+// matchBook reports whether the rule matches the book.
+func (r rule) matchBook(bk book.Book) bool {
+	if r.min != 0 && (bk.ID < r.min || bk.ID > r.max) {
+		return false
+	}
+	return r.title == nil || r.title.MatchString(norm.Fold(bk.Title.String()))
+}
+
+// Book reports whether the whole book is ignored, the matched rule is
+// returned for the logs.
+func (rs Rules) Book(bk book.Book) (ignored bool, by string) {
+	for _, r := range rs.rules {
+		if r.category == "" && r.format == "" && r.matchBook(bk) {
+			ignored, by = !r.negate, r.source
+		}
+	}
+	return ignored, by
+}
+
+// Material reports whether the materials of the category in the format are
+// ignored for the book, the empty format means the materials which have no
+// format, the matched rule is returned for the logs.
+func (rs Rules) Material(bk book.Book, category, format string) (ignored bool, by string) {
+	for _, r := range rs.rules {
+		if r.category != "" && r.category != category {
+			continue
+		}
+		if r.format != "" && r.format != strings.ToLower(format) {
+			continue
+		}
+		if r.matchBook(bk) {
+			ignored, by = !r.negate, r.source
+		}
+	}
+	return ignored, by
+}
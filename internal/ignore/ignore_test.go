@@ -0,0 +1,103 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ignore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/xorcare/miflib.go/internal/book"
+)
+
+func TestRules(t *testing.T) {
+	rules, err := Parse([]byte(`
+# huge audio collections
+Аудиоколлекция*
+!аудиоколлекция: избранное
+120-180
+!id:150
+format:FB2
+title:"Путь *" category:audiobook
+id:42 category:ebook format:pdf
+category:videos
+`))
+	require.NoError(t, err)
+
+	tests := []struct {
+		book      book.Book
+		ignored   bool
+		by        string
+		materials map[[2]string]bool
+	}{
+		{
+			book:    book.Book{ID: 1, Title: "Аудиоколлекция: всё о продажах"},
+			ignored: true,
+			by:      "Аудиоколлекция*",
+		},
+		{
+			book: book.Book{ID: 2, Title: "Аудиоколлекция: Избранное"},
+			by:   "!аудиоколлекция: избранное",
+			materials: map[[2]string]bool{
+				{"audiobook", "mp3"}: false,
+				{"ebook", "fb2"}:     true,
+				{"demo", "FB2"}:      true,
+				{"videos", ""}:       true,
+				{"cover", ""}:        false,
+			},
+		},
+		{book: book.Book{ID: 130, Title: "Книга"}, ignored: true, by: "120-180"},
+		{book: book.Book{ID: 150, Title: "Книга"}, by: "!id:150"},
+		{
+			book: book.Book{ID: 42, Title: "Путь джедая"},
+			materials: map[[2]string]bool{
+				{"audiobook", "mp3"}: true,
+				{"audiobook", ""}:    true,
+				{"ebook", "pdf"}:     true,
+				{"ebook", "epub"}:    false,
+				{"ebook", ""}:        false,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.book.Title.String(), func(t *testing.T) {
+			ignored, by := rules.Book(tt.book)
+			require.Equal(t, tt.ignored, ignored)
+			require.Equal(t, tt.by, by)
+
+			for material, want := range tt.materials {
+				ignored, _ := rules.Material(tt.book, material[0], material[1])
+				require.Equal(t, want, ignored, material)
+			}
+		})
+	}
+
+	t.Run("zero", func(t *testing.T) {
+		ignored, _ := Rules{}.Book(book.Book{ID: 1})
+		require.False(t, ignored)
+	})
+}
+
+func TestParse_error(t *testing.T) {
+	tests := map[string]string{
+		"id:abc":                   `line 1: invalid book id "abc"`,
+		"180-120":                  `line 1: invalid range of book ids "180-120"`,
+		"42 size:100":              `line 1: unknown term "size", expected one of: id, title, category, format`,
+		"\n\ncategory:":            `line 3: empty value of the term "category:"`,
+		`title:"Путь *`:            `line 1: unterminated quoted string in "title:\"Путь *"`,
+		"!":                        `line 1: empty rule "!"`,
+		"format:mp3 title:\"\" 42": `line 1: empty value of the term "title:"`,
+	}
+	for data, want := range tests {
+		t.Run(data, func(t *testing.T) {
+			_, err := Parse([]byte(data))
+			require.EqualError(t, err, want)
+		})
+	}
+
+	rules, err := Read("testdata/not-exist")
+	require.NoError(t, err)
+	require.Equal(t, Rules{}, rules)
+}
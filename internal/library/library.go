@@ -0,0 +1,153 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package library
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/xorcare/miflib.go/internal/book"
+	"github.com/xorcare/miflib.go/internal/osutil"
+)
+
+// Names of the service files of the library.
+const (
+	// BookFile contains the catalog entry of the book, it's placed in the
+	// directory of each book.
+	BookFile = "book.json"
+	// LockFile marks a book whose materials are completely downloaded, it's
+	// placed in the directory of each book.
+	LockFile = ".downloaded"
+	// ManifestFile lists the files of the book with their sizes and SHA-256
+	// checksums, it's placed in the directory of each downloaded book.
+	ManifestFile = "manifest.json"
+	// VideosFile lists the videos of the book with their titles and
+	// durations, it's placed in the directory of the book.
+	VideosFile = "videos.json"
+	// AuthorsDir it's the directory in the library root which contains the
+	// portraits of the authors shared by all books.
+	AuthorsDir = ".authors"
+	// IgnoreFile it's the file in the library root which lists the books
+	// and the materials that should never be downloaded.
+	IgnoreFile = ".miflibignore"
+)
+
+// Entry it's a book found in the local library.
+type Entry struct {
+	// Path is the directory of the book.
+	Path string
+	// Book is the catalog entry of the book read from the BookFile.
+	Book book.Book
+	// Downloaded reports whether the book has the LockFile.
+	Downloaded bool
+}
+
+// Scan walks the library root and returns all books found in it sorted
+// by their identifiers. The directory of a book is recognized by the
+// presence of the BookFile, its subdirectories are not scanned.
+func Scan(root string) ([]Entry, error) {
+	var entries []Entry
+	err := filepath.Walk(root, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+
+		exist, err := osutil.FileExists(filepath.Join(name, BookFile))
+		if err != nil {
+			return err
+		}
+		if !exist {
+			// Hidden service directories are skipped, but a book directory
+			// starting with a dot is still recognized by its book file.
+			if name != root && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		entry, err := Read(name)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Book.ID < entries[j].Book.ID
+	})
+
+	return entries, nil
+}
+
+// Read reads the book located in the directory.
+func Read(dir string) (Entry, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, BookFile))
+	if err != nil {
+		return Entry{}, err
+	}
+
+	entry := Entry{Path: dir}
+	if err := json.Unmarshal(data, &entry.Book); err != nil {
+		return Entry{}, fmt.Errorf("library: %s: %v", filepath.Join(dir, BookFile), err)
+	}
+
+	entry.Downloaded, err = osutil.FileExists(filepath.Join(dir, LockFile))
+
+	return entry, err
+}
+
+// Find searches the library for the book with the identifier.
+func Find(root string, id int) (Entry, error) {
+	entries, err := Scan(root)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	for _, entry := range entries {
+		if entry.Book.ID == id {
+			return entry, nil
+		}
+	}
+
+	return Entry{}, fmt.Errorf("library: book with id %d not found in %q", id, root)
+}
+
+// Remove removes the directory of the book and its parent directories left
+// empty up to the library root.
+func Remove(root string, entry Entry) error {
+	if err := os.RemoveAll(entry.Path); err != nil {
+		return err
+	}
+
+	for dir := filepath.Dir(entry.Path); inside(root, dir); dir = filepath.Dir(dir) {
+		if infos, err := ioutil.ReadDir(dir); err != nil || len(infos) > 0 {
+			return nil
+		}
+		if err := os.Remove(dir); err != nil {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// inside reports whether the directory is nested in the root.
+func inside(root, dir string) bool {
+	rel, err := filepath.Rel(root, dir)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package library
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemove(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	for dir, content := range map[string]string{
+		"Дорофеев/Джедайские техники": `{"id": 42, "title": "Джедайские техники"}`,
+		"Дорофеев/Путь джедая":        `{"id": 180, "title": "Путь джедая"}`,
+		"Петров/Ёлки/книга":           `{"id": 120, "title": "Ёлки"}`,
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Join(tempDir, dir), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(tempDir, dir, BookFile), []byte(content), 0644))
+	}
+
+	entries, err := Scan(tempDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	for _, entry := range entries[:2] {
+		require.NoError(t, Remove(tempDir, entry))
+	}
+
+	entries, err = Scan(tempDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, 180, entries[0].Book.ID)
+	require.DirExists(t, filepath.Join(tempDir, "Дорофеев"))
+	require.NoDirExists(t, filepath.Join(tempDir, "Петров"))
+	require.DirExists(t, tempDir)
+}
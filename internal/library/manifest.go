@@ -0,0 +1,85 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package library
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Manifest it's the list of the files of the book stored in the
+// ManifestFile.
+type Manifest struct {
+	Files []ManifestEntry `json:"files"`
+}
+
+// ManifestEntry it's the file of the book recorded to the manifest.
+type ManifestEntry struct {
+	// Path is the slash separated path of the file relative to the
+	// directory of the book.
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+	// URL is the address from which the file was downloaded, empty if the
+	// file is created by the loader.
+	URL string `json:"url,omitempty"`
+	// CatalogSize is the size of the file in the catalog, zero if unknown.
+	CatalogSize uint `json:"catalog_size,omitempty"`
+}
+
+// ReadManifest reads the manifest of the book located in the directory.
+func ReadManifest(dir string) (Manifest, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return Manifest{}, fmt.Errorf("library: %s: %v", filepath.Join(dir, ManifestFile), err)
+	}
+
+	return m, nil
+}
+
+// WriteManifest writes the manifest to the directory of the book, the
+// files are ordered by their paths.
+func WriteManifest(dir string, m Manifest) error {
+	m.Files = append([]ManifestEntry(nil), m.Files...)
+	sort.Slice(m.Files, func(i, j int) bool {
+		return m.Files[i].Path < m.Files[j].Path
+	})
+
+	data, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(dir, ManifestFile), append(data, '\n'), 0644)
+}
+
+// Checksum returns the hex encoded SHA-256 checksum and the size of the
+// file.
+func Checksum(filename string) (sum string, size int64, err error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if size, err = io.Copy(h, file); err != nil {
+		return "", 0, err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
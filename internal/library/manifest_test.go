@@ -0,0 +1,43 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package library
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestManifest(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	filename := filepath.Join(tempDir, "book.epub")
+	require.NoError(t, ioutil.WriteFile(filename, []byte("hello"), 0644))
+
+	sum, size, err := Checksum(filename)
+	require.NoError(t, err)
+	require.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", sum)
+	require.Equal(t, int64(5), size)
+
+	_, err = ReadManifest(tempDir)
+	require.True(t, os.IsNotExist(err))
+
+	m := Manifest{Files: []ManifestEntry{
+		{Path: "photos/cover.jpg", Size: 1, SHA256: "00"},
+		{Path: "book.epub", Size: size, SHA256: sum, URL: "https://example.com/book.epub", CatalogSize: 5},
+	}}
+	require.NoError(t, WriteManifest(tempDir, m))
+
+	got, err := ReadManifest(tempDir)
+	require.NoError(t, err)
+	require.Equal(t, "book.epub", got.Files[0].Path)
+	require.Equal(t, m.Files[0], got.Files[1])
+	require.Equal(t, uint(5), got.Files[0].CatalogSize)
+}
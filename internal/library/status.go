@@ -0,0 +1,131 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package library
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/xorcare/miflib.go/internal/book"
+)
+
+// State it's the state of the book of the local library compared with the
+// catalog.
+type State string
+
+// States of the books.
+const (
+	// StateNew it's the book of the catalog which is missing in the library.
+	StateNew State = "new"
+	// StatePartial it's the book which download is not completed.
+	StatePartial State = "partial"
+	// StateComplete it's the completely downloaded book which metadata
+	// matches the catalog.
+	StateComplete State = "complete"
+	// StateUpdated it's the completely downloaded book which metadata
+	// differs from the catalog.
+	StateUpdated State = "updated"
+	// StateOrphaned it's the book of the library which is removed from the
+	// catalog.
+	StateOrphaned State = "orphaned"
+)
+
+// States it's all states in the order of the report.
+var States = []State{StateNew, StatePartial, StateUpdated, StateOrphaned, StateComplete}
+
+// Status it's the state of the single book.
+type Status struct {
+	ID    int
+	Title string
+	State State
+	// Path is the directory of the book, empty for the new books.
+	Path string
+	// Remote is the size of the files of the book in the catalog, the
+	// files of unknown size are not counted.
+	Remote uint64
+	// Local is the size of the files in the directory of the book.
+	Local uint64
+}
+
+// Compare compares the books of the library with the catalog, the result
+// is ordered by the identifiers of the books.
+func Compare(entries []Entry, catalog []book.Book) ([]Status, error) {
+	remote := make(map[int]book.Book, len(catalog))
+	for _, bk := range catalog {
+		remote[bk.ID] = bk
+	}
+
+	statuses := make([]Status, 0, len(catalog))
+	local := make(map[int]bool, len(entries))
+	for _, entry := range entries {
+		local[entry.Book.ID] = true
+
+		size, err := Size(entry.Path)
+		if err != nil {
+			return nil, err
+		}
+		st := Status{ID: entry.Book.ID, Title: entry.Book.Title.String(), Path: entry.Path, Local: size}
+
+		bk, ok := remote[entry.Book.ID]
+		switch {
+		case !ok:
+			st.State = StateOrphaned
+		case !entry.Downloaded:
+			st.State, st.Remote = StatePartial, bk.Files.Size()
+		default:
+			st.State, st.Remote = StateComplete, bk.Files.Size()
+			if changed, err := metadataChanged(entry.Book, bk); err != nil {
+				return nil, err
+			} else if changed {
+				st.State = StateUpdated
+			}
+		}
+		statuses = append(statuses, st)
+	}
+
+	for _, bk := range catalog {
+		if !local[bk.ID] {
+			statuses = append(statuses, Status{ID: bk.ID, Title: bk.Title.String(), State: StateNew, Remote: bk.Files.Size()})
+		}
+	}
+
+	sort.SliceStable(statuses, func(i, j int) bool {
+		return statuses[i].ID < statuses[j].ID
+	})
+
+	return statuses, nil
+}
+
+// metadataChanged reports whether the catalog entry of the book differs
+// from the recorded one.
+func metadataChanged(recorded, bk book.Book) (bool, error) {
+	old, err := json.Marshal(recorded)
+	if err != nil {
+		return false, err
+	}
+	cur, err := json.Marshal(bk)
+	if err != nil {
+		return false, err
+	}
+	return !bytes.Equal(old, cur), nil
+}
+
+// Size returns the total size of the regular files in the directory and
+// its subdirectories, the links are not followed.
+func Size(dir string) (n uint64, err error) {
+	err = filepath.Walk(dir, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			n += uint64(info.Size())
+		}
+		return nil
+	})
+	return n, err
+}
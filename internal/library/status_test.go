@@ -0,0 +1,77 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package library
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/xorcare/miflib.go/internal/book"
+)
+
+func TestCompare(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	epub := func(size uint) book.Files {
+		return book.Files{Books: book.Formats{"epub": {{URL: "https://example.com/book.epub", Size: size}}}}
+	}
+	catalog := []book.Book{
+		{ID: 42, Title: "Джедайские техники", Files: epub(10)},
+		{ID: 120, Title: "Ёлки", Files: epub(20)},
+		{ID: 150, Title: "Пиши, сокращай", Files: epub(30)},
+		{ID: 180, Title: "Путь джедая", Files: epub(40)},
+	}
+
+	write := func(dir string, bk book.Book, downloaded bool) {
+		dir = filepath.Join(tempDir, dir)
+		require.NoError(t, os.MkdirAll(dir, 0755))
+		data, err := json.Marshal(bk)
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, BookFile), data, 0644))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "book.epub"), []byte("epub"), 0644))
+		if downloaded {
+			require.NoError(t, ioutil.WriteFile(filepath.Join(dir, LockFile), nil, 0644))
+		}
+	}
+	write("Джедайские техники", catalog[0], true)
+	write("Ёлки", catalog[1], false)
+	updated := catalog[2]
+	updated.Title = "Пиши"
+	write("Пиши, сокращай", updated, true)
+	write("Удалённая", book.Book{ID: 7, Title: "Удалённая"}, true)
+
+	entries, err := Scan(tempDir)
+	require.NoError(t, err)
+
+	statuses, err := Compare(entries, catalog)
+	require.NoError(t, err)
+
+	got := make(map[int]State, len(statuses))
+	ids := make([]int, 0, len(statuses))
+	for _, st := range statuses {
+		got[st.ID] = st.State
+		ids = append(ids, st.ID)
+	}
+	require.Equal(t, []int{7, 42, 120, 150, 180}, ids)
+	require.Equal(t, map[int]State{
+		7:   StateOrphaned,
+		42:  StateComplete,
+		120: StatePartial,
+		150: StateUpdated,
+		180: StateNew,
+	}, got)
+
+	require.Equal(t, uint64(40), statuses[4].Remote)
+	require.Empty(t, statuses[4].Path)
+	require.Zero(t, statuses[0].Remote)
+	require.NotZero(t, statuses[0].Local)
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package logging builds the loggers of the subsystems of the application
+// which share the output but have their own levels.
+package logging
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Subsystems of the application.
+const (
+	API        = "api"
+	Downloader = "downloader"
+	CLI        = "cli"
+)
+
+// Subsystems it's the list of the subsystems which levels can be set.
+var Subsystems = []string{API, Downloader, CLI}
+
+// Formats of the output.
+const (
+	FormatConsole = "console"
+	FormatJSON    = "json"
+)
+
+// Config it's the configuration of the loggers.
+type Config struct {
+	// Format is the format of the output, FormatConsole by default.
+	Format string
+	// File is the file of the output, the standard error is used if it's
+	// empty.
+	File string
+	// MaxSize is the size of the file in bytes after which the file is
+	// rotated, zero disables the rotation.
+	MaxSize uint64
+	// MaxBackups is the number of the rotated files to keep.
+	MaxBackups int
+	// Levels is the levels of the subsystems, the empty subsystem is the
+	// level of the subsystems which are not listed.
+	Levels map[string]zapcore.Level
+	// Sampling enables the sampling of the repeated messages, some
+	// messages are dropped under load then.
+	Sampling bool
+}
+
+// Logger it's the source of the loggers of the subsystems.
+type Logger struct {
+	encoder zapcore.Encoder
+	sink    zapcore.WriteSyncer
+	closer  func() error
+	cfg     Config
+}
+
+// New creates the loggers configured by the config.
+func New(cfg Config) (*Logger, error) {
+	var encoder zapcore.Encoder
+	switch cfg.Format {
+	case FormatConsole, "":
+		encoder = zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig())
+	case FormatJSON:
+		conf := zap.NewProductionEncoderConfig()
+		conf.EncodeTime = zapcore.ISO8601TimeEncoder
+		encoder = zapcore.NewJSONEncoder(conf)
+	default:
+		return nil, fmt.Errorf("logging: unknown format %q, expected one of: %s, %s",
+			cfg.Format, FormatConsole, FormatJSON)
+	}
+
+	l := &Logger{
+		encoder: encoder,
+		sink:    zapcore.Lock(os.Stderr),
+		closer:  func() error { return nil },
+		cfg:     cfg,
+	}
+	if cfg.File != "" {
+		file, err := openRotator(cfg.File, cfg.MaxSize, cfg.MaxBackups)
+		if err != nil {
+			return nil, err
+		}
+		l.sink, l.closer = file, file.Close
+	}
+
+	return l, nil
+}
+
+// Named returns the logger of the subsystem.
+func (l *Logger) Named(subsystem string) *zap.Logger {
+	level, ok := l.cfg.Levels[subsystem]
+	if !ok {
+		level = l.cfg.Levels[""]
+	}
+
+	core := zapcore.NewCore(l.encoder.Clone(), l.sink, level)
+	if l.cfg.Sampling {
+		core = zapcore.NewSampler(core, time.Second, 100, 100)
+	}
+
+	return zap.New(core).Named(subsystem)
+}
+
+// Close flushes and closes the output.
+func (l *Logger) Close() error {
+	if err := l.sink.Sync(); err != nil && l.cfg.File != "" {
+		return err
+	}
+	return l.closer()
+}
+
+// ParseLevels parses the comma separated levels, the level without the
+// subsystem is the level of all subsystems, for example: info,api=debug.
+func ParseLevels(s string) (map[string]zapcore.Level, error) {
+	levels := map[string]zapcore.Level{"": zapcore.InfoLevel}
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		subsystem, name := "", field
+		if i := strings.IndexByte(field, '='); i >= 0 {
+			subsystem, name = strings.TrimSpace(field[:i]), strings.TrimSpace(field[i+1:])
+			if !known(subsystem) {
+				return nil, fmt.Errorf("logging: unknown subsystem %q, expected one of: %s",
+					subsystem, strings.Join(Subsystems, ", "))
+			}
+		}
+
+		var level zapcore.Level
+		if err := level.UnmarshalText([]byte(name)); err != nil {
+			return nil, fmt.Errorf("logging: unknown level %q", name)
+		}
+		levels[subsystem] = level
+	}
+
+	return levels, nil
+}
+
+func known(subsystem string) bool {
+	for _, name := range Subsystems {
+		if name == subsystem {
+			return true
+		}
+	}
+	return false
+}
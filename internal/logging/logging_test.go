@@ -0,0 +1,109 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package logging
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestParseLevels(t *testing.T) {
+	levels, err := ParseLevels("")
+	require.NoError(t, err)
+	require.Equal(t, map[string]zapcore.Level{"": zapcore.InfoLevel}, levels)
+
+	levels, err = ParseLevels("warn, api=debug,downloader = error")
+	require.NoError(t, err)
+	require.Equal(t, map[string]zapcore.Level{
+		"":         zapcore.WarnLevel,
+		API:        zapcore.DebugLevel,
+		Downloader: zapcore.ErrorLevel,
+	}, levels)
+
+	_, err = ParseLevels("db=debug")
+	require.EqualError(t, err, `logging: unknown subsystem "db", expected one of: api, downloader, cli`)
+
+	_, err = ParseLevels("api=loud")
+	require.EqualError(t, err, `logging: unknown level "loud"`)
+}
+
+func TestLogger(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	filename := filepath.Join(tempDir, "logs", "miflib.log")
+	levels, err := ParseLevels("warn,api=debug")
+	require.NoError(t, err)
+
+	l, err := New(Config{Format: FormatJSON, File: filename, Levels: levels})
+	require.NoError(t, err)
+
+	l.Named(API).Sugar().Debugw("send the http request", "url", "https://example.com")
+	l.Named(Downloader).Sugar().Infow("start downloading the book", "book_id", 42)
+	l.Named(Downloader).Sugar().Warnw("skip the undiscovered file", "book_id", 42, "bytes", 10)
+	require.NoError(t, l.Close())
+
+	data, err := ioutil.ReadFile(filename)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	require.Equal(t, "debug", entry["level"])
+	require.Equal(t, API, entry["logger"])
+	require.Equal(t, "https://example.com", entry["url"])
+
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &entry))
+	require.Equal(t, Downloader, entry["logger"])
+	require.Equal(t, float64(42), entry["book_id"])
+	require.Equal(t, float64(10), entry["bytes"])
+
+	_, err = New(Config{Format: "xml"})
+	require.EqualError(t, err, `logging: unknown format "xml", expected one of: console, json`)
+}
+
+func TestRotator(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	filename := filepath.Join(tempDir, "miflib.log")
+	r, err := openRotator(filename, 10, 2)
+	require.NoError(t, err)
+	for _, entry := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		_, err := r.Write([]byte(entry))
+		require.NoError(t, err)
+	}
+	require.NoError(t, r.Close())
+
+	for name, want := range map[string]string{
+		"miflib.log":   "fourth\n",
+		"miflib.log.1": "third\n",
+		"miflib.log.2": "second\n",
+	} {
+		data, err := ioutil.ReadFile(filepath.Join(tempDir, name))
+		require.NoError(t, err)
+		require.Equal(t, want, string(data), name)
+	}
+	require.NoFileExists(t, filepath.Join(tempDir, "miflib.log.3"))
+
+	r, err = openRotator(filename, 10, 0)
+	require.NoError(t, err)
+	_, err = r.Write([]byte("fifth\n"))
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	data, err := ioutil.ReadFile(filename)
+	require.NoError(t, err)
+	require.Equal(t, "fifth\n", string(data))
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// rotator it's the log file which is rotated by the size, the rotated
+// files are named file.1, file.2 and so on, file.1 is the newest one.
+type rotator struct {
+	mu       sync.Mutex
+	filename string
+	maxSize  uint64
+	backups  int
+	file     *os.File
+	size     uint64
+}
+
+func openRotator(filename string, maxSize uint64, backups int) (*rotator, error) {
+	r := &rotator{filename: filename, maxSize: maxSize, backups: backups}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return nil, err
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *rotator) open() error {
+	file, err := os.OpenFile(r.filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	r.file, r.size = file, uint64(info.Size())
+
+	return nil
+}
+
+// Write writes the entry to the file, the file is rotated before the
+// write if the entry doesn't fit into the size.
+func (r *rotator) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.maxSize != 0 && r.size != 0 && r.size+uint64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += uint64(n)
+
+	return n, err
+}
+
+func (r *rotator) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+
+	if r.backups <= 0 {
+		if err := os.Remove(r.filename); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return r.open()
+	}
+
+	for i := r.backups - 1; i >= 0; i-- {
+		oldpath := r.filename
+		if i > 0 {
+			oldpath += "." + strconv.Itoa(i)
+		}
+		err := os.Rename(oldpath, r.filename+"."+strconv.Itoa(i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return r.open()
+}
+
+// Sync commits the written entries to the disk.
+func (r *rotator) Sync() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Sync()
+}
+
+// Close closes the file.
+func (r *rotator) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
@@ -0,0 +1,16 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package norm
+
+import (
+	"strings"
+)
+
+// Fold it's function provides a representation of a string for the
+// case-insensitive comparison, the letters ё and е are considered equal.
+func Fold(s string) string {
+	s = strings.ToLower(String(s))
+	return strings.ReplaceAll(s, "ё", "е")
+}
@@ -284,3 +284,8 @@ func TestString(t *testing.T) {
 		})
 	}
 }
+
+func TestFold(t *testing.T) {
+	require.Equal(t, "елки-палки: как не сойти с ума", Fold("  Ёлки-палки: Как НЕ сойти с ума "))
+	require.Equal(t, Fold("ДЖЕДАЙСКИЕ ТЕХНИКИ"), Fold("джедайские техники"))
+}
@@ -0,0 +1,38 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package osutil
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// ErrFreeSpaceUnsupported it's the error returned by FreeSpace on the
+// platforms where the free space can't be determined.
+var ErrFreeSpaceUnsupported = errors.New("osutil: free space is not supported on this platform")
+
+// FreeSpace returns the number of bytes available to the unprivileged user
+// on the file system containing the path, the path may not exist yet, the
+// nearest existing parent directory is used then.
+func FreeSpace(path string) (uint64, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			break
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			break
+		}
+		path = parent
+	}
+
+	return freeSpace(path)
+}
@@ -0,0 +1,12 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux && !darwin && !freebsd && !windows
+// +build !linux,!darwin,!freebsd,!windows
+
+package osutil
+
+func freeSpace(string) (uint64, error) {
+	return 0, ErrFreeSpaceUnsupported
+}
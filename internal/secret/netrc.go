@@ -0,0 +1,124 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package secret
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Netrc it's the entry of the .netrc file.
+type Netrc struct {
+	Login    string
+	Password string
+}
+
+// NetrcPath returns the path of the .netrc file, the NETRC environment
+// variable overrides the file in the home directory.
+func NetrcPath() string {
+	if filename := os.Getenv("NETRC"); filename != "" {
+		return filename
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	if runtime.GOOS == "windows" {
+		return filepath.Join(home, "_netrc")
+	}
+	return filepath.Join(home, ".netrc")
+}
+
+// LookupNetrc returns the entry of the machine from the .netrc file, the
+// host is compared with and without the port, the default entry is used if
+// there is no entry of the machine. The missing file contains no entries.
+func LookupNetrc(filename, host string) (entry Netrc, ok bool, err error) {
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return Netrc{}, false, nil
+	} else if err != nil {
+		return Netrc{}, false, err
+	}
+
+	entries := parseNetrc(string(data))
+	hostname := host
+	if i := strings.LastIndexByte(host, ':'); i >= 0 {
+		hostname = host[:i]
+	}
+	for _, name := range []string{host, hostname, ""} {
+		if entry, ok := entries[name]; ok {
+			return entry, true, nil
+		}
+	}
+
+	return Netrc{}, false, nil
+}
+
+// parseNetrc returns the entries of the machines, the default entry has the
+// empty name, the first entry of the machine wins.
+func parseNetrc(data string) map[string]Netrc {
+	entries := make(map[string]Netrc)
+	var (
+		machine string
+		entry   Netrc
+		active  bool
+		macro   bool
+	)
+	save := func() {
+		if _, ok := entries[machine]; active && !ok {
+			entries[machine] = entry
+		}
+	}
+
+	for _, line := range strings.Split(data, "\n") {
+		if macro {
+			// the body of the macro ends with the empty line.
+			macro = strings.TrimSpace(line) != ""
+			continue
+		}
+		fields := strings.Fields(line)
+		for i := 0; i < len(fields); i++ {
+			// the comment starts with the token in place of the keyword, the
+			// values such as the passwords may contain the '#'.
+			if strings.HasPrefix(fields[i], "#") {
+				break
+			}
+			switch fields[i] {
+			case "machine", "default":
+				save()
+				machine, entry, active = "", Netrc{}, true
+				if fields[i] == "machine" && i+1 < len(fields) {
+					i++
+					machine = fields[i]
+				}
+			case "login":
+				if i+1 < len(fields) {
+					i++
+					entry.Login = fields[i]
+				}
+			case "password":
+				if i+1 < len(fields) {
+					i++
+					entry.Password = fields[i]
+				}
+			case "account":
+				i++
+			case "macdef":
+				// the macros are skipped, the rest of the line is the name
+				// of the macro.
+				save()
+				active, macro = false, true
+				i = len(fields)
+			}
+		}
+	}
+	save()
+
+	return entries
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package secret
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// ErrNoTerminal it's the error of the prompt if the input is not a terminal
+// or the terminal is not supported on the platform.
+var ErrNoTerminal = errors.New("secret: the input is not a terminal")
+
+// IsTerminal reports whether the file is a terminal.
+func IsTerminal(f *os.File) bool {
+	return isTerminal(f.Fd())
+}
+
+// exitInterrupted it's the exit code of the process interrupted during the
+// prompt if the signal can't be raised again.
+const exitInterrupted = 130
+
+// Prompt writes the prompt and reads the password from the terminal with
+// the echo disabled. The echo is restored on every return and on the
+// interrupt or the termination of the process during the prompt.
+func Prompt(in *os.File, out io.Writer, prompt string) (string, error) {
+	if !IsTerminal(in) {
+		return "", ErrNoTerminal
+	}
+
+	fmt.Fprint(out, prompt)
+	defer fmt.Fprintln(out)
+
+	restore, err := disableEcho(in.Fd())
+	if err != nil {
+		return "", err
+	}
+	var once sync.Once
+	restoreOnce := func() { once.Do(restore) }
+	defer restoreOnce()
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case sig := <-sigs:
+			restoreOnce()
+			fmt.Fprintln(out)
+			// the signal is raised again to terminate the process as it
+			// would be without the prompt.
+			signal.Stop(sigs)
+			if p, err := os.FindProcess(os.Getpid()); err != nil || p.Signal(sig) != nil {
+				os.Exit(exitInterrupted)
+			}
+		case <-done:
+		}
+	}()
+
+	return firstLine(in)
+}
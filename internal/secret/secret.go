@@ -0,0 +1,74 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package secret reads the password of the library from the sources which
+// don't leak it into the shell history and the process list: a file, the
+// standard input, the output of a command, the .netrc file or the terminal.
+package secret
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// ErrEmpty it's the error of the source which contains no password.
+var ErrEmpty = errors.New("secret: the password is empty")
+
+// FromFile reads the password from the first line of the file.
+func FromFile(filename string) (string, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+
+	return firstLine(bytes.NewReader(data))
+}
+
+// FromReader reads the password from the first line of the reader.
+func FromReader(r io.Reader) (string, error) {
+	return firstLine(r)
+}
+
+// FromCommand runs the command by the shell and reads the password from
+// the first line of its output, for example: pass show miflib.
+func FromCommand(ctx context.Context, command string) (string, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+
+	stderr := bytes.Buffer{}
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("secret: the command %q failed: %v: %s", command, err, msg)
+		}
+		return "", fmt.Errorf("secret: the command %q failed: %v", command, err)
+	}
+
+	return firstLine(bytes.NewReader(out))
+}
+
+func firstLine(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return "", ErrEmpty
+	}
+
+	return line, nil
+}
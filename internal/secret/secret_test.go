@@ -0,0 +1,103 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package secret
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromFile(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	filename := filepath.Join(tempDir, "password")
+	require.NoError(t, ioutil.WriteFile(filename, []byte("p@ss word\r\nsecond line\n"), 0600))
+	password, err := FromFile(filename)
+	require.NoError(t, err)
+	require.Equal(t, "p@ss word", password)
+
+	require.NoError(t, ioutil.WriteFile(filename, []byte("\n"), 0600))
+	_, err = FromFile(filename)
+	require.Equal(t, ErrEmpty, err)
+
+	_, err = FromFile(filepath.Join(tempDir, "not-exist"))
+	require.True(t, os.IsNotExist(err))
+
+	password, err = FromReader(strings.NewReader("without newline"))
+	require.NoError(t, err)
+	require.Equal(t, "without newline", password)
+
+	f, err := os.Open(filename)
+	require.NoError(t, err)
+	defer f.Close()
+	require.False(t, IsTerminal(f))
+	_, err = Prompt(f, &bytes.Buffer{}, "Password: ")
+	require.Equal(t, ErrNoTerminal, err)
+}
+
+func TestFromCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test commands require sh")
+	}
+
+	password, err := FromCommand(context.Background(), "printf 'secret\\nmetadata\\n'")
+	require.NoError(t, err)
+	require.Equal(t, "secret", password)
+
+	_, err = FromCommand(context.Background(), "echo 'no such entry' >&2; exit 3")
+	require.EqualError(t, err, `secret: the command "echo 'no such entry' >&2; exit 3" failed: exit status 3: no such entry`)
+}
+
+func TestLookupNetrc(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	filename := filepath.Join(tempDir, ".netrc")
+	require.NoError(t, ioutil.WriteFile(filename, []byte(`
+# the library
+machine miflib.example.com login user@example.com password secret
+machine localhost:8080
+	login test
+	password test-secret # the local server
+machine hash.example.com login #user password p#ss
+macdef init
+machine evil.example.com login evil password evil
+
+machine miflib.example.com login other password other
+default login anonymous password guest
+`), 0600))
+
+	tests := map[string]Netrc{
+		"miflib.example.com":      {Login: "user@example.com", Password: "secret"},
+		"miflib.example.com:8443": {Login: "user@example.com", Password: "secret"},
+		"localhost:8080":          {Login: "test", Password: "test-secret"},
+		"hash.example.com":        {Login: "#user", Password: "p#ss"},
+		"evil.example.com":        {Login: "anonymous", Password: "guest"},
+		"unknown.example.com":     {Login: "anonymous", Password: "guest"},
+	}
+	for host, want := range tests {
+		t.Run(host, func(t *testing.T) {
+			entry, ok, err := LookupNetrc(filename, host)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, want, entry)
+		})
+	}
+
+	_, ok, err := LookupNetrc(filepath.Join(tempDir, "not-exist"), "localhost")
+	require.NoError(t, err)
+	require.False(t, ok)
+}
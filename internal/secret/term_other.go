@@ -0,0 +1,16 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux && !darwin && !freebsd && !windows
+// +build !linux,!darwin,!freebsd,!windows
+
+package secret
+
+func isTerminal(uintptr) bool {
+	return false
+}
+
+func disableEcho(uintptr) (restore func(), err error) {
+	return nil, ErrNoTerminal
+}
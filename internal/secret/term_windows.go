@@ -0,0 +1,31 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package secret
+
+import (
+	"syscall"
+)
+
+const enableEchoInput = 0x0004
+
+var setConsoleMode = syscall.NewLazyDLL("kernel32.dll").NewProc("SetConsoleMode")
+
+func isTerminal(fd uintptr) bool {
+	var mode uint32
+	return syscall.GetConsoleMode(syscall.Handle(fd), &mode) == nil
+}
+
+func disableEcho(fd uintptr) (restore func(), err error) {
+	var mode uint32
+	if err := syscall.GetConsoleMode(syscall.Handle(fd), &mode); err != nil {
+		return nil, err
+	}
+
+	if ret, _, err := setConsoleMode.Call(fd, uintptr(mode&^enableEchoInput)); ret == 0 {
+		return nil, err
+	}
+
+	return func() { _, _, _ = setConsoleMode.Call(fd, uintptr(mode)) }, nil
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package translit
+
+import (
+	"strings"
+)
+
+// https://en.wikipedia.org/wiki/GOST_7.79-2000
+// https://en.wikipedia.org/wiki/ISO_9
+// https://en.wikipedia.org/wiki/Romanization_of_Russian
+
+// gostTable it's the table of the system B of GOST 7.79-2000.
+var gostTable = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "j", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "x", 'ц': "cz", 'ч': "ch", 'ш': "sh", 'щ': "shh",
+	'ъ': "``", 'ы': "y'", 'ь': "`", 'э': "e`", 'ю': "yu", 'я': "ya",
+	// Ukrainian and Belarusian letters.
+	'ґ': "g`", 'є': "ye", 'і': "i", 'ї': "yi", 'ў': "u`",
+}
+
+// gostRule it's the rule of GOST 7.79-2000 by which the letter ц is
+// transliterated as c before i, e, y and j.
+func gostRule(r rune, next string) (string, bool) {
+	if r == 'ц' && next != "" && strings.IndexByte("eijy", next[0]) >= 0 {
+		return "c", true
+	}
+	return "", false
+}
+
+// iso9Table it's the table of ISO 9:1995 which is the same as the system A
+// of GOST 7.79-2000.
+var iso9Table = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "ë",
+	'ж': "ž", 'з': "z", 'и': "i", 'й': "j", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "h", 'ц': "c", 'ч': "č", 'ш': "š", 'щ': "ŝ",
+	'ъ': "ʺ", 'ы': "y", 'ь': "ʹ", 'э': "è", 'ю': "û", 'я': "â",
+	// Ukrainian and Belarusian letters.
+	'ґ': "g̀", 'є': "ê", 'і': "ì", 'ї': "ï", 'ў': "ŭ",
+}
+
+// simpleTable it's the practical scheme, the hard and soft signs are
+// omitted.
+var simpleTable = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	// Ukrainian and Belarusian letters.
+	'ґ': "g", 'є': "ye", 'і': "i", 'ї': "yi", 'ў': "w",
+}
+
+// punctuation it's the table of the non-ASCII punctuation and signs
+// replaced by their ASCII counterparts.
+var punctuation = map[rune]string{
+	'«': `"`, '»': `"`, '„': `"`, '“': `"`, '”': `"`, '‘': "'", '’': "'",
+	'‚': "'", '‹': "'", '›': "'", 'ʹ': "'", 'ʺ': `"`,
+	'‐': "-", '‑': "-", '‒': "-", '–': "-", '—': "-", '―': "-", '−': "-",
+	'…': "...", '№': "No", '·': ".", '•': "-",
+	'\u00a0': " ", '\u2009': " ", '\u202f': " ",
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package translit transliterates the Cyrillic text to the Latin script.
+package translit
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Names of the transliteration schemes.
+const (
+	// None disables the transliteration.
+	None = ""
+	// GOST it's the system B of GOST 7.79-2000, it uses only ASCII letters
+	// and a few punctuation marks.
+	GOST = "gost"
+	// ISO9 it's ISO 9:1995, the one-to-one system which uses Latin letters
+	// with diacritics, the diacritics are dropped to keep the text ASCII, so
+	// the result is not reversible.
+	ISO9 = "iso9"
+	// Simple it's the practical scheme without apostrophes and diacritics
+	// close to the spelling of the Russian names in the passports.
+	Simple = "simple"
+)
+
+// Scheme it's the rules of the transliteration.
+type Scheme struct {
+	name  string
+	table map[rune]string
+	// rule returns the replacement of the letter depending on the
+	// transliteration of the next letter, ok is false if the table
+	// should be used.
+	rule func(r rune, next string) (s string, ok bool)
+}
+
+var schemes = map[string]Scheme{
+	None:   {},
+	GOST:   {name: GOST, table: gostTable, rule: gostRule},
+	ISO9:   {name: ISO9, table: iso9Table},
+	Simple: {name: Simple, table: simpleTable},
+}
+
+// Lookup returns the transliteration scheme by the name, the empty name
+// returns the scheme which leaves the text as is.
+func Lookup(name string) (Scheme, error) {
+	scheme, ok := schemes[name]
+	if !ok {
+		return Scheme{}, fmt.Errorf("translit: unknown transliteration scheme %q", name)
+	}
+
+	return scheme, nil
+}
+
+// Name returns the name of the scheme.
+func (s Scheme) Name() string {
+	return s.name
+}
+
+// String transliterates the Cyrillic letters of the text to ASCII, the
+// common punctuation is replaced by its ASCII counterpart, the diacritics
+// are dropped and the other non-ASCII characters are replaced by the
+// underscore. The letters transliterated to several Latin letters are
+// capitalized as the whole word if the neighbouring letters are in upper
+// case.
+func (s Scheme) String(text string) string {
+	if s.table == nil {
+		return text
+	}
+
+	runes := []rune(text)
+	buf := strings.Builder{}
+	buf.Grow(len(text))
+	for i, r := range runes {
+		lower := unicode.ToLower(r)
+		latin, ok := s.letter(lower, runes[i+1:])
+		if !ok {
+			buf.WriteRune(r)
+			continue
+		}
+
+		if r != lower {
+			latin = capitalize(latin, upperWord(runes, i))
+		}
+		buf.WriteString(latin)
+	}
+
+	return ascii(buf.String())
+}
+
+// ascii folds the text to ASCII.
+func ascii(text string) string {
+	buf := strings.Builder{}
+	buf.Grow(len(text))
+	for _, r := range norm.NFD.String(text) {
+		switch {
+		case r < utf8.RuneSelf:
+			buf.WriteRune(r)
+		case unicode.Is(unicode.Mn, r):
+			// the diacritics are dropped.
+		default:
+			if s, ok := punctuation[r]; ok {
+				buf.WriteString(s)
+			} else {
+				buf.WriteByte('_')
+			}
+		}
+	}
+
+	return buf.String()
+}
+
+// letter returns the transliteration of the lower case letter.
+func (s Scheme) letter(r rune, rest []rune) (string, bool) {
+	latin, ok := s.table[r]
+	if !ok {
+		return "", false
+	}
+
+	if s.rule != nil && len(rest) > 0 {
+		next := s.table[unicode.ToLower(rest[0])]
+		if latin, ok := s.rule(r, next); ok {
+			return latin, true
+		}
+	}
+
+	return latin, true
+}
+
+// capitalize converts the transliteration of the upper case letter to
+// upper case, only the first letter is converted if the word is not in
+// upper case.
+func capitalize(s string, word bool) string {
+	if word || s == "" {
+		return strings.ToUpper(s)
+	}
+
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+// upperWord reports whether the letter at the position i is a part of
+// the word in upper case, the next letter is checked or the previous one
+// if the letter is the last in the word.
+func upperWord(runes []rune, i int) bool {
+	if i+1 < len(runes) && unicode.IsLetter(runes[i+1]) {
+		return unicode.IsUpper(runes[i+1])
+	}
+	if i > 0 && unicode.IsLetter(runes[i-1]) {
+		return unicode.IsUpper(runes[i-1])
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2020 Vasiliy Vasilyuk All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package translit
+
+import (
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheme_String(t *testing.T) {
+	tests := []struct {
+		scheme string
+		text   string
+		want   string
+	}{
+		{scheme: None, text: "Джедайские техники", want: "Джедайские техники"},
+		{scheme: GOST, text: "Джедайские техники", want: "Dzhedajskie texniki"},
+		{scheme: GOST, text: "Цыганская Цапля и цех", want: "Cy'ganskaya Czaplya i cex"},
+		{scheme: GOST, text: "Подъезд, объём, щука", want: "Pod``ezd, ob``yom, shhuka"},
+		{scheme: ISO9, text: "Джедайские техники", want: "Dzedajskie tehniki"},
+		{scheme: ISO9, text: "Щука и ёж", want: "Suka i ez"},
+		{scheme: ISO9, text: "Объём", want: `Ob"em`},
+		{scheme: Simple, text: "Джедайские техники", want: "Dzhedayskie tekhniki"},
+		{scheme: Simple, text: "Подъезд, Щука, ЩУКА", want: "Podezd, Shchuka, SHCHUKA"},
+		{scheme: Simple, text: "Я и ЯЩИК", want: "Ya i YASHCHIK"},
+		{scheme: Simple, text: "ОБЪЁМ", want: "OBEM"},
+		{scheme: Simple, text: "Книга 2.0: «Мысли»", want: `Kniga 2.0: "Mysli"`},
+		{scheme: GOST, text: "Глава — 1… № 5", want: "Glava - 1... No 5"},
+		{scheme: Simple, text: "Café über 東京", want: "Cafe uber __"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.scheme+" "+tt.text, func(t *testing.T) {
+			scheme, err := Lookup(tt.scheme)
+			require.NoError(t, err)
+			require.Equal(t, tt.scheme, scheme.Name())
+			require.Equal(t, tt.want, scheme.String(tt.text))
+			require.Equal(t, tt.want, scheme.String(tt.text), "must be deterministic")
+			if tt.scheme != None {
+				for _, r := range scheme.String(tt.text) {
+					require.True(t, r < utf8.RuneSelf, "must be ASCII, got %q", r)
+				}
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	_, err := Lookup("bgn")
+	require.EqualError(t, err, `translit: unknown transliteration scheme "bgn"`)
+}